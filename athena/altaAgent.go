@@ -2,6 +2,9 @@ package main
 
 import (
 	"errors"
+	"fmt"
+	"strconv"
+	"strings"
 
 	"github.com/contiv/symphony/pkg/altaspec"
 	"github.com/contiv/symphony/pkg/libdocker"
@@ -16,6 +19,53 @@ type AltaState struct {
 	portNames    []string
 	spec         altaspec.AltaSpec
 	containerCtx *libdocker.ContainerCtx
+	liveness     *ProbeRunner // liveness probe, if any
+	readiness    *ProbeRunner // readiness probe, if any
+}
+
+// Start health check probes for the alta
+func (self *AltaState) startProbes() {
+	// Stop any old probes
+	self.stopProbes()
+
+	if self.spec.LivenessProbe != nil {
+		self.liveness = NewProbeRunner(self.AltaId, "liveness", self.spec.LivenessProbe,
+			self.containerCtx)
+	}
+	if self.spec.ReadinessProbe != nil {
+		self.readiness = NewProbeRunner(self.AltaId, "readiness", self.spec.ReadinessProbe,
+			self.containerCtx)
+	}
+}
+
+// Stop health check probes
+func (self *AltaState) stopProbes() {
+	if self.liveness != nil {
+		self.liveness.Stop()
+		self.liveness = nil
+	}
+	if self.readiness != nil {
+		self.readiness.Stop()
+		self.readiness = nil
+	}
+}
+
+// Check if liveness probe declared the container dead
+func (self *AltaState) LivenessFailed() bool {
+	if self.liveness == nil {
+		return false
+	}
+
+	return self.liveness.Failed()
+}
+
+// Check if container is ready. Containers without readiness probe are always ready
+func (self *AltaState) IsReady() bool {
+	if self.readiness == nil {
+		return true
+	}
+
+	return self.readiness.Healthy()
 }
 
 // Database of alta instances
@@ -69,6 +119,16 @@ func (self *AltaMgr) CreateAlta(altaSpec altaspec.AltaSpec) (*AltaState, error)
 
 	log.Infof("Volume spec: %+v\n, Volume binds: %+v\n", altaSpec.Volumes, volumeBinds)
 
+	// Jobs run to completion. Dont let docker restart them
+	restartPolicy := "on-failure"
+	if altaSpec.Kind == "job" {
+		restartPolicy = "no"
+	}
+
+	// Let the container know about the resources allocated to it
+	envList := append([]string{}, altaSpec.EnvList...)
+	envList = append(envList, resourceEnvs(altaSpec.AllocatedResources)...)
+
 	// Convert Alta spec to container spec
 	containerSpec := libdocker.ContainerSpec{
 		Name:       altaSpec.AltaName,
@@ -78,11 +138,11 @@ func (self *AltaMgr) CreateAlta(altaSpec altaspec.AltaSpec) (*AltaState, error)
 		Image:      altaSpec.Image,
 		Command:    altaSpec.Command,
 		Args:       altaSpec.Args,
-		Envs:       altaSpec.EnvList,
+		Envs:       envList,
 		WorkingDir: altaSpec.WorkingDir,
 
 		Privileged:        false,
-		RestartPolicyName: "on-failure",
+		RestartPolicyName: restartPolicy,
 		RestartRetryCount: 5,
 
 		ExposePorts: altaSpec.ExposePorts,
@@ -91,6 +151,9 @@ func (self *AltaMgr) CreateAlta(altaSpec altaspec.AltaSpec) (*AltaState, error)
 		NetworkMode: "none",
 
 		VolumeBinds: volumeBinds, // Volumes to be bind mounted
+
+		// Mark the container as owned by symphony
+		Labels: map[string]string{altaspec.AltaIdLabel: altaId},
 	}
 
 	// Create the docker container
@@ -132,9 +195,45 @@ func (self *AltaMgr) FindAltaByContainerId(containerId string) *AltaState {
 	return nil
 }
 
-// List all Alta containers on this node
-func (self *AltaMgr) ListContainers() ([]string, error) {
-	return libdocker.GetRunningContainers()
+// List all containers(running or exited) on this node
+func (self *AltaMgr) ListContainers() ([]libdocker.ContainerInfo, error) {
+	return libdocker.GetContainerInfoList()
+}
+
+// Remove a container owned by symphony that master does not know about
+func (self *AltaMgr) RemoveOrphanContainer(containerId string) error {
+	// Get container context
+	dockerCtx, err := libdocker.GetContainer(containerId)
+	if err != nil {
+		log.Errorf("Error getting container: %s. Err: %v", containerId, err)
+		return err
+	}
+
+	// Never touch containers we did not create
+	if dockerCtx.GetContainerLabels()[altaspec.AltaIdLabel] == "" {
+		log.Errorf("Container %s is not owned by symphony. Not removing it", containerId)
+		return errors.New("Container not owned by symphony")
+	}
+
+	// If we have state for this container, clean it up
+	altaState := self.FindAltaByContainerId(containerId)
+	if altaState != nil {
+		self.StopAlta(altaState.AltaId)
+		delete(self.altaDb, altaState.AltaId)
+	} else {
+		dockerCtx.StopContainer()
+	}
+
+	// Remove the container
+	err = dockerCtx.RemoveContainer()
+	if err != nil {
+		log.Errorf("Error removing the container %s, Error %v", containerId, err)
+		return err
+	}
+
+	log.Infof("Removed orphan container %s", containerId)
+
+	return nil
 }
 
 // Get detailed info about a specific alta
@@ -204,6 +303,9 @@ func (self *AltaMgr) StartAlta(altaId string) error {
 		}
 	}
 
+	// Start health checks
+	altaState.startProbes()
+
 	return nil
 }
 
@@ -216,6 +318,9 @@ func (self *AltaMgr) StopAlta(altaId string) error {
 		return errors.New("Alta does not exists")
 	}
 
+	// Stop health checks
+	altaState.stopProbes()
+
 	// Stop the container
 	err := altaState.containerCtx.StopContainer()
 	if err != nil {
@@ -244,6 +349,9 @@ func (self *AltaMgr) RemoveAlta(altaId string) error {
 		return errors.New("Alta does not exists")
 	}
 
+	// Stop health checks
+	altaState.stopProbes()
+
 	// Stop the container
 	err := altaState.containerCtx.RemoveContainer()
 	if err != nil {
@@ -256,3 +364,39 @@ func (self *AltaMgr) RemoveAlta(altaId string) error {
 
 	return nil
 }
+
+// Build environment variables for allocated resources. For a resource type
+// "host-port" this sets SYMPHONY_RESOURCE_HOST_PORT to the allocated indexes
+// for descrete resources, or to the amount for fluid resources
+func resourceEnvs(rsrcList []altaspec.AllocatedResource) []string {
+	var envList []string
+
+	for _, rsrc := range rsrcList {
+		// cpu and memory are already set as container limits
+		if (rsrc.Type == "cpu") || (rsrc.Type == "memory") {
+			continue
+		}
+
+		// Convert resource type to env variable name
+		envName := strings.Map(func(r rune) rune {
+			if ((r >= 'a') && (r <= 'z')) || ((r >= 'A') && (r <= 'Z')) || ((r >= '0') && (r <= '9')) {
+				return r
+			}
+			return '_'
+		}, strings.ToUpper(rsrc.Type))
+
+		// List of indexes for descrete resources
+		value := strconv.FormatFloat(rsrc.NumRsrc, 'f', -1, 64)
+		if len(rsrc.RsrcIndexes) != 0 {
+			var indexes []string
+			for _, idx := range rsrc.RsrcIndexes {
+				indexes = append(indexes, strconv.FormatUint(idx, 10))
+			}
+			value = strings.Join(indexes, ",")
+		}
+
+		envList = append(envList, fmt.Sprintf("SYMPHONY_RESOURCE_%s=%s", envName, value))
+	}
+
+	return envList
+}
@@ -2,8 +2,11 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
+	"sync"
+	"time"
 
 	"github.com/contiv/objmodel/objdb"
 	"github.com/contiv/symphony/pkg/altaspec"
@@ -56,6 +59,7 @@ func createRouter() *mux.Router {
 		},
 		"DELETE": {
 			"/alta/{altaId}":         httpRemoveAlta,
+			"/container/{cntId}":     httpRemoveContainer,
 			"/images/{altaId}":       httpRemoveImage,
 			"/network/{networkName}": httpRemoveNetwork,
 			"/peer/{peerAddr}":       httpRemovePeer,
@@ -80,6 +84,24 @@ func createRouter() *mux.Router {
 		}
 	}
 
+	// Routes that stream their response instead of returning json
+	streamRouteMap := map[string]map[string]http.HandlerFunc{
+		"GET": {
+			"/alta/{altaId}/logs": httpGetAltaLogs,
+		},
+		"POST": {
+			"/alta/{altaId}/exec": httpPostAltaExec,
+		},
+	}
+
+	// Register each streaming method/path
+	for method, routes := range streamRouteMap {
+		for route, funct := range routes {
+			log.Infof("Registering %s %s", method, route)
+			router.Path(route).Methods(method).HandlerFunc(funct)
+		}
+	}
+
 	return router
 }
 
@@ -171,17 +193,27 @@ func httpGetAltaList(w http.ResponseWriter, r *http.Request, vars map[string]str
 
 	// Build a list of container ctx
 	var altaList []altaspec.AltaContext
-	for _, cid := range containerList {
+	for _, cinfo := range containerList {
 		var altaId string
+		cid := cinfo.Id
+		livenessFailed := false
+		ready := false
 		altaState := altaMgr.FindAltaByContainerId(cid)
 		if altaState == nil {
 			altaId = ""
 		} else {
 			altaId = altaState.AltaId
+			livenessFailed = altaState.LivenessFailed()
+			ready = altaState.IsReady()
 		}
 		altaContext := altaspec.AltaContext{
-			AltaId:      altaId,
-			ContainerId: cid,
+			AltaId:         altaId,
+			ContainerId:    cid,
+			LivenessFailed: livenessFailed,
+			Ready:          ready,
+			Running:        cinfo.Running,
+			ExitCode:       cinfo.ExitCode,
+			Owned:          (cinfo.Labels[altaspec.AltaIdLabel] != ""),
 		}
 
 		altaList = append(altaList, altaContext)
@@ -197,6 +229,163 @@ func httpGetAltaInfo(w http.ResponseWriter, r *http.Request, vars map[string]str
 	return nil, nil
 }
 
+// Writer that flushes each write to the http client
+type flushWriter struct {
+	writer  http.ResponseWriter
+	mutex   sync.Mutex
+	written bool
+}
+
+func (self *flushWriter) Write(p []byte) (int, error) {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	self.written = true
+	n, err := self.writer.Write(p)
+	if flusher, ok := self.writer.(http.Flusher); ok {
+		flusher.Flush()
+	}
+
+	return n, err
+}
+
+// Parse log options from query parameters
+func parseLogOptions(r *http.Request) (libdocker.LogOptions, error) {
+	var opts libdocker.LogOptions
+	query := r.URL.Query()
+
+	opts.Tail = query.Get("tail")
+	opts.Timestamps = (query.Get("timestamps") == "true")
+	opts.Follow = (query.Get("follow") == "true")
+
+	// since can be RFC3339 time or unix timestamp
+	since := query.Get("since")
+	if since != "" {
+		sinceTime, err := time.Parse(time.RFC3339, since)
+		if err != nil {
+			secs, err := strconv.ParseInt(since, 10, 64)
+			if err != nil {
+				log.Errorf("Invalid since time %s", since)
+				return opts, errors.New("Invalid since time")
+			}
+			sinceTime = time.Unix(secs, 0)
+		}
+		opts.Since = sinceTime
+	}
+
+	return opts, nil
+}
+
+// Stream logs of an alta container
+func httpGetAltaLogs(w http.ResponseWriter, r *http.Request) {
+	altaId := mux.Vars(r)["altaId"]
+
+	log.Infof("Received GET alta logs for %s: %s", altaId, r.RequestURI)
+
+	// Find the alta
+	altaState := altaMgr.ListAlta()[altaId]
+	if altaState == nil {
+		log.Errorf("Could not find Alta %s", altaId)
+		http.Error(w, "Alta does not exists", http.StatusNotFound)
+		return
+	}
+
+	// Parse the options
+	opts, err := parseLogOptions(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain")
+
+	// Stream the logs
+	fw := &flushWriter{writer: w}
+	err = altaState.containerCtx.GetContainerLog(opts, fw, fw)
+	if err != nil {
+		log.Errorf("Error getting logs for alta %s. Err: %v", altaId, err)
+
+		// We can only send an error if nothing was streamed yet
+		if !fw.written {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	}
+}
+
+// Execute a command in an alta container.
+// Interactive requests hijack the connection and stream raw stdin/stdout
+func httpPostAltaExec(w http.ResponseWriter, r *http.Request) {
+	altaId := mux.Vars(r)["altaId"]
+	var execReq altaspec.AltaExecReq
+
+	// Find the alta
+	altaState := altaMgr.ListAlta()[altaId]
+	if altaState == nil {
+		log.Errorf("Could not find Alta %s", altaId)
+		http.Error(w, "Alta does not exists", http.StatusNotFound)
+		return
+	}
+
+	// Get exec parameters from the request
+	err := json.NewDecoder(r.Body).Decode(&execReq)
+	if err != nil || len(execReq.Command) == 0 {
+		log.Errorf("Error decoding exec request. Err %v", err)
+		http.Error(w, "Invalid exec request", http.StatusBadRequest)
+		return
+	}
+
+	log.Infof("Executing %v in alta %s", execReq.Command, altaId)
+
+	// One-shot commands return the output as json
+	if !execReq.Interactive {
+		result, err := altaState.containerCtx.ExecCmd(execReq.Command)
+		if err != nil {
+			log.Errorf("Error executing %v in alta %s. Err: %v", execReq.Command, altaId, err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		execResp := altaspec.AltaExecResp{
+			Stdout:   result.Stdout,
+			Stderr:   result.Stderr,
+			ExitCode: result.ExitCode,
+		}
+		writeJSON(w, http.StatusOK, execResp)
+		return
+	}
+
+	// Interactive sessions need an upgraded connection
+	hijacker, ok := w.(http.Hijacker)
+	if !ok || (r.Header.Get("Upgrade") == "") {
+		http.Error(w, "Interactive exec requires connection upgrade", http.StatusBadRequest)
+		return
+	}
+
+	conn, bufrw, err := hijacker.Hijack()
+	if err != nil {
+		log.Errorf("Error hijacking connection. Err: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer conn.Close()
+
+	// Let the client know we are switching to raw stream
+	bufrw.WriteString("HTTP/1.1 101 UPGRADED\r\n")
+	bufrw.WriteString("Content-Type: application/vnd.docker.raw-stream\r\n")
+	bufrw.WriteString("Connection: Upgrade\r\nUpgrade: tcp\r\n\r\n")
+	bufrw.Flush()
+
+	// Run the command attached to the connection
+	exitCode, err := altaState.containerCtx.ExecInteractive(execReq.Command, execReq.Tty,
+		bufrw.Reader, conn)
+	if err != nil {
+		log.Errorf("Error executing %v in alta %s. Err: %v", execReq.Command, altaId, err)
+		return
+	}
+
+	log.Infof("Interactive exec %v in alta %s exited with %d", execReq.Command, altaId, exitCode)
+}
+
 // Check if an image present on the host
 func httpPostIsImagePresent(w http.ResponseWriter, r *http.Request, vars map[string]string) (interface{}, error) {
 	log.Infof("Received POST isImagePresent: %+v", vars)
@@ -366,6 +555,26 @@ func httpRemoveAlta(w http.ResponseWriter, r *http.Request, vars map[string]stri
 	return removeResp, nil
 }
 
+// Remove an orphan container
+func httpRemoveContainer(w http.ResponseWriter, r *http.Request, vars map[string]string) (interface{}, error) {
+	containerId := vars["cntId"]
+
+	// Remove the container
+	err := altaMgr.RemoveOrphanContainer(containerId)
+	if err != nil {
+		log.Errorf("Error removing container %s, Error: %v", containerId, err)
+		return nil, err
+	}
+
+	// Create response
+	removeResp := altaspec.ReqSuccess{
+		Success: true,
+	}
+
+	// Send response
+	return removeResp, nil
+}
+
 // Remove an image
 func httpRemoveImage(w http.ResponseWriter, r *http.Request, vars map[string]string) (interface{}, error) {
 
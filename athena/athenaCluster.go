@@ -18,7 +18,9 @@ package main
 // This file has all clustering related stuff..
 
 import (
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"time"
 
@@ -29,6 +31,9 @@ import (
 	log "github.com/Sirupsen/logrus"
 )
 
+// File listing extra resources this node provides
+const extraResourceFile = "/etc/athena/resources.json"
+
 type ClusterAgent struct {
 	localIp   string // Local IP address
 	apiPortNo int    // port number where we are listening
@@ -128,9 +133,56 @@ func (self *ClusterAgent) getNodeSpec() altaspec.NodeSpec {
 		},
 	}
 
+	// Add any extra resources configured on this node
+	nodeSpec.Resources = append(nodeSpec.Resources, readExtraResources(extraResourceFile)...)
+
 	return nodeSpec
 }
 
+// Read additional resources like licenses, host ports or local disk
+// from a json file containing a list of resources
+func readExtraResources(fileName string) []altaspec.Resource {
+	var extraRsrcs []altaspec.Resource
+
+	// Extra resources are optional
+	data, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Errorf("Error reading resource file %s. Err: %v", fileName, err)
+		}
+		return nil
+	}
+
+	err = json.Unmarshal(data, &extraRsrcs)
+	if err != nil {
+		log.Errorf("Error parsing resource file %s. Err: %v", fileName, err)
+		return nil
+	}
+
+	// Skip invalid resources
+	var rsrcList []altaspec.Resource
+	for _, rsrc := range extraRsrcs {
+		if (rsrc.Type == "") || (rsrc.Type == "cpu") || (rsrc.Type == "memory") {
+			log.Errorf("Ignoring invalid resource type in %s: %+v", fileName, rsrc)
+			continue
+		}
+		if (rsrc.UnitType != "fluid") && (rsrc.UnitType != "descrete") {
+			log.Errorf("Ignoring resource with invalid unit type in %s: %+v", fileName, rsrc)
+			continue
+		}
+		if rsrc.NumRsrc <= 0 {
+			log.Errorf("Ignoring resource with no units in %s: %+v", fileName, rsrc)
+			continue
+		}
+
+		rsrcList = append(rsrcList, rsrc)
+	}
+
+	log.Infof("Read extra resources %+v from %s", rsrcList, fileName)
+
+	return rsrcList
+}
+
 // Periodically send container info to all masters
 func (self *ClusterAgent) monitorContainers() {
 	for {
@@ -3,7 +3,9 @@ package main
 import (
 	"errors"
 	"net"
+	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/contiv/ofnet"
@@ -19,13 +21,23 @@ const USABLE_VLAN_START = 2
 const USABLE_VLAN_END = 4094
 const OVS_CONTROLLER_PORT = 6633
 
+// VLAN networks are switched by a bridge without controller, so that OVS does
+// normal L2 switching between the VLAN ports and the physical uplink
+const VLAN_BRIDGE_NAME = "vlanbr0"
+const VLAN_PORT_PREFIX = "vlanport"
+
+// Environment variable naming the physical uplink for VLAN networks
+const UPLINK_INTF_ENV = "SYMPHONY_UPLINK_INTF"
+
 type NetAgent struct {
 	ovsDriver  *ovsdriver.OvsDriver
+	vlanDriver *ovsdriver.OvsDriver // Bridge for VLAN networks
 	ofnetAgent *ofnet.OfnetAgent
 
 	networkDb  map[string]*altaspec.AltaNetSpec
 	vlanBitset *bitset.BitSet     // Allocated Vlan Ids
 	peerHostDb map[string]*string // Remote host IP addresses
+	uplinkIntf string             // Physical uplink for VLAN networks
 
 	currPortNum int // Current OVS port number
 	currVtepNum int // Current VTEP port number
@@ -60,6 +72,16 @@ func NewNetAgent() *NetAgent {
 	netAgent.currPortNum = 1
 	netAgent.currVtepNum = 1
 
+	// Create the VLAN bridge and add the uplink to it as a trunk port
+	netAgent.vlanDriver = ovsdriver.NewOvsDriverForBridge(VLAN_BRIDGE_NAME)
+	netAgent.uplinkIntf = os.Getenv(UPLINK_INTF_ENV)
+	if (netAgent.uplinkIntf != "") && !netAgent.vlanDriver.IsPortNamePresent(netAgent.uplinkIntf) {
+		err = netAgent.vlanDriver.CreatePort(netAgent.uplinkIntf, "", 0)
+		if err != nil {
+			log.Fatalf("Error adding uplink %s to OVS. Err: %v", netAgent.uplinkIntf, err)
+		}
+	}
+
 	// Initialise the DB
 	netAgent.peerHostDb = make(map[string]*string)
 	netAgent.networkDb = make(map[string]*altaspec.AltaNetSpec)
@@ -99,40 +121,97 @@ func (self *NetAgent) CreateNetwork(netSpec altaspec.AltaNetSpec) error {
 		return nil
 	}
 
-	// if network already exists, just update state and return
-	if self.networkDb[netSpec.NetworkName] != nil {
+	// if network already exists with same encap, nothing to do.
+	// Otherwise, network was recreated while we missed the delete
+	if oldSpec := self.networkDb[netSpec.NetworkName]; oldSpec != nil {
+		if (oldSpec.Encap == netSpec.Encap) && (oldSpec.Vni == netSpec.Vni) &&
+			((netSpec.Encap != "vlan") || (oldSpec.VlanId == netSpec.VlanId)) {
+			return nil
+		}
+
+		self.DeleteNetwork(netSpec.NetworkName)
+	}
+
+	if netSpec.Encap == "vlan" {
+		// VLAN networks are bridged onto the uplink. Make sure no VXLAN network
+		// is using the VLAN id as local VLAN
+		if self.vlanBitset.Test(uint(netSpec.VlanId)) {
+			log.Errorf("Vlan %d of net %s is already in use", netSpec.VlanId, netSpec.NetworkName)
+			return errors.New("Vlan is already in use")
+		}
+		if self.uplinkIntf == "" {
+			log.Warnf("No uplink for vlan net %s. Set %s to reach other nodes",
+				netSpec.NetworkName, UPLINK_INTF_ENV)
+		}
+		self.vlanBitset.Set(uint(netSpec.VlanId))
+
+		// Add it to the DB
 		self.networkDb[netSpec.NetworkName] = &netSpec
+
 		return nil
 	}
 
-	// Add it to the DB
-	self.networkDb[netSpec.NetworkName] = &netSpec
+	// VXLAN networks get a local VLAN on this node
+	localVlan, err := self.allocLocalVlan()
+	if err != nil {
+		log.Errorf("Error allocating local vlan for net %+v. Err: %v", netSpec, err)
+		return err
+	}
+	netSpec.VlanId = localVlan
 
 	// Add vlan mapping
-	err := netAgent.ofnetAgent.AddVlan(netSpec.VlanId, netSpec.Vni)
+	err = netAgent.ofnetAgent.AddVlan(netSpec.VlanId, netSpec.Vni)
 	if err != nil {
 		log.Errorf("Error adding vlan for net %+v. Err: %v", netSpec, err)
+		self.vlanBitset.Clear(uint(localVlan))
 		return err
 	}
 
+	// Add it to the DB
+	self.networkDb[netSpec.NetworkName] = &netSpec
+
 	return nil
 }
 
+// Allocate a local VLAN for a VXLAN network. Allocate from the top so that
+// we dont collide with VLAN networks that use the low range
+func (self *NetAgent) allocLocalVlan() (uint16, error) {
+	for vlanId := uint(USABLE_VLAN_END); vlanId >= USABLE_VLAN_START; vlanId-- {
+		if !self.vlanBitset.Test(vlanId) {
+			self.vlanBitset.Set(vlanId)
+			return uint16(vlanId), nil
+		}
+	}
+
+	return 0, errors.New("No free vlan")
+}
+
 // Delete a network
 func (self *NetAgent) DeleteNetwork(name string) error {
-	// Check if the network exists
+	// Network is pushed only to nodes that run its altas. So, zeus deletes
+	// networks we never had
 	if self.networkDb[name] == nil {
-		log.Errorf("Network %s not found", name)
-		return errors.New("Network not found")
+		log.Infof("Network %s not found. Nothing to delete", name)
+		return nil
+	}
+
+	// Default network is always there
+	if name == "default" {
+		return errors.New("Default network can not be deleted")
 	}
 
-	// Remove the VLAN mapping
+	// Remove the VLAN mapping of VXLAN networks
 	network := self.networkDb[name]
-	err := netAgent.ofnetAgent.RemoveVlan(network.VlanId, network.Vni)
-	if err != nil {
-		log.Errorf("Error removing vlan for net %+v. Err: %v", network, err)
+	if network.Encap != "vlan" {
+		err := netAgent.ofnetAgent.RemoveVlan(network.VlanId, network.Vni)
+		if err != nil {
+			log.Errorf("Error removing vlan for net %+v. Err: %v", network, err)
+		}
 	}
 
+	// Release the VLAN
+	self.vlanBitset.Clear(uint(network.VlanId))
+
 	// Remove it from the DB
 	delete(self.networkDb, name)
 
@@ -160,19 +239,27 @@ func (self *NetAgent) createEndpoint(NetworkName string) (string, error) {
 		return "", err
 	}
 
+	// VLAN network ports go on the VLAN bridge. Port name tells them apart
+	ovsDriver := self.ovsDriver
+	portPrefix := "ovsport"
+	if netState.Encap == "vlan" {
+		ovsDriver = self.vlanDriver
+		portPrefix = VLAN_PORT_PREFIX
+	}
+
 	// Derive a port name
 	// FIXME: We need to do better job of recycling port numbers
-	portName := "ovsport" + strconv.Itoa(self.currPortNum)
+	portName := portPrefix + strconv.Itoa(self.currPortNum)
 	for {
 		self.currPortNum++
-		if !self.ovsDriver.IsPortNamePresent(portName) {
+		if !ovsDriver.IsPortNamePresent(portName) {
 			break
 		}
-		portName = "ovsport" + strconv.Itoa(self.currPortNum)
+		portName = portPrefix + strconv.Itoa(self.currPortNum)
 	}
 
 	// Create the OVS port
-	err = self.ovsDriver.CreatePort(portName, "internal", uint(netState.VlanId))
+	err = ovsDriver.CreatePort(portName, "internal", uint(netState.VlanId))
 	if err != nil {
 		log.Errorf("Error creating a port. Err %v", err)
 		return "", err
@@ -210,6 +297,10 @@ func (self *NetAgent) CreateAltaEndpoint(contPid int, ifNum int, ifSpec *altaspe
 		IPAddr:     ifSpec.IntfIpv4Addr,
 		NetmaskLen: ifSpec.IntfIpv4Masklen,
 		DefaultGw:  ifSpec.Ipv4Gateway,
+
+		IPv6Addr:       ifSpec.IntfIpv6Addr,
+		IPv6NetmaskLen: ifSpec.IntfIpv6Masklen,
+		DefaultGwV6:    ifSpec.Ipv6Gateway,
 	}
 
 	// Rename the intf inside the namespace and assign Mac and IP address
@@ -220,6 +311,11 @@ func (self *NetAgent) CreateAltaEndpoint(contPid int, ifNum int, ifSpec *altaspe
 		return "", err
 	}
 
+	// VLAN network ports are switched by OVS, there is nothing to add to ofnet
+	if strings.HasPrefix(portName, VLAN_PORT_PREFIX) {
+		return portName, nil
+	}
+
 	// Get OFP port number
 	ofpPort, err := self.ovsDriver.GetOfpPortNo(portName)
 	if err != nil {
@@ -234,6 +330,14 @@ func (self *NetAgent) CreateAltaEndpoint(contPid int, ifNum int, ifSpec *altaspe
 		return "", err
 	}
 
+	// The vrouter datapath only routes IPv4, so zeus rejects IPv6 networks.
+	// Networks created before that can still have IPv6 addresses, but ofnet
+	// installs no IPv6 routes or neighbor discovery flows for them
+	if ifSpec.IntfIpv6Addr != "" {
+		log.Warnf("IPv6 address %s of port %s is not forwarded by vrouter datapath",
+			ifSpec.IntfIpv6Addr, portName)
+	}
+
 	intfMac, _ := net.ParseMAC(ifSpec.IntfMacAddr)
 	endpoint := ofnet.EndpointInfo{
 		PortNo:  ofpPort,
@@ -254,6 +358,16 @@ func (self *NetAgent) CreateAltaEndpoint(contPid int, ifNum int, ifSpec *altaspe
 
 // Delete the interface
 func (self *NetAgent) DeleteAltaEndpoint(portName string) error {
+	// VLAN network ports are not in ofnet, just delete them from VLAN bridge
+	if strings.HasPrefix(portName, VLAN_PORT_PREFIX) {
+		err := self.vlanDriver.DeletePort(portName)
+		if err != nil {
+			log.Errorf("Error deleting port %s. Error: %v", portName, err)
+		}
+
+		return err
+	}
+
 	// Get OFP port number
 	ofpPort, err := self.ovsDriver.GetOfpPortNo(portName)
 	if err != nil {
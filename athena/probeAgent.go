@@ -0,0 +1,220 @@
+package main
+
+// This file implements container health checks(liveness/readiness probes)
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/contiv/symphony/pkg/altaspec"
+	"github.com/contiv/symphony/pkg/libdocker"
+	"github.com/contiv/symphony/pkg/netutils"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// Probe defaults
+const (
+	defaultProbeInterval  = 10 // seconds between probes
+	defaultProbeTimeout   = 1  // seconds to wait for a probe
+	defaultProbeThreshold = 3  // consecutive failures to declare failure
+)
+
+// State of a running probe
+type ProbeRunner struct {
+	altaId       string                  // Alta being probed
+	probeName    string                  // liveness or readiness
+	probe        altaspec.AltaProbe      // Probe spec
+	containerCtx *libdocker.ContainerCtx // container to probe
+
+	mutex     sync.Mutex    // Lock for counters
+	failures  int           // Consecutive failures
+	successes int           // Consecutive successes
+	healthy   bool          // Current health state
+	failed    bool          // Failure threshold was reached
+	stopChan  chan struct{} // Channel to stop the probe
+}
+
+// Create a probe runner and start probing in background
+func NewProbeRunner(altaId, probeName string, probe *altaspec.AltaProbe,
+	containerCtx *libdocker.ContainerCtx) *ProbeRunner {
+	runner := new(ProbeRunner)
+
+	runner.altaId = altaId
+	runner.probeName = probeName
+	runner.probe = *probe
+	runner.containerCtx = containerCtx
+	runner.stopChan = make(chan struct{})
+
+	// Fill in the defaults
+	if runner.probe.Interval <= 0 {
+		runner.probe.Interval = defaultProbeInterval
+	}
+	if runner.probe.Timeout <= 0 {
+		runner.probe.Timeout = defaultProbeTimeout
+	}
+	if runner.probe.FailureThreshold <= 0 {
+		runner.probe.FailureThreshold = defaultProbeThreshold
+	}
+	if runner.probe.SuccessThreshold <= 0 {
+		runner.probe.SuccessThreshold = 1
+	}
+
+	// Start the probe loop
+	go runner.runLoop()
+
+	return runner
+}
+
+// Stop probing
+func (self *ProbeRunner) Stop() {
+	close(self.stopChan)
+}
+
+// Check if failure threshold was reached
+func (self *ProbeRunner) Failed() bool {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	return self.failed
+}
+
+// Check if success threshold was reached
+func (self *ProbeRunner) Healthy() bool {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	return self.healthy
+}
+
+// Periodically run the probe
+func (self *ProbeRunner) runLoop() {
+	// Wait for initial delay
+	select {
+	case <-time.After(time.Duration(self.probe.InitialDelay) * time.Second):
+	case <-self.stopChan:
+		return
+	}
+
+	ticker := time.NewTicker(time.Duration(self.probe.Interval) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		// Run the probe and update the counters
+		err := self.runProbe()
+		self.updateResult(err)
+
+		select {
+		case <-ticker.C:
+		case <-self.stopChan:
+			return
+		}
+	}
+}
+
+// Update counters based on probe result
+func (self *ProbeRunner) updateResult(err error) {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	if err != nil {
+		self.successes = 0
+		self.failures++
+		log.Warnf("%s probe failed for alta %s(%d times). Err: %v", self.probeName,
+			self.altaId, self.failures, err)
+
+		if self.failures >= self.probe.FailureThreshold {
+			self.healthy = false
+			self.failed = true
+		}
+	} else {
+		self.failures = 0
+		self.successes++
+
+		if self.successes >= self.probe.SuccessThreshold {
+			self.healthy = true
+			self.failed = false
+		}
+	}
+}
+
+// Run the probe once
+func (self *ProbeRunner) runProbe() error {
+	timeout := time.Duration(self.probe.Timeout) * time.Second
+
+	switch self.probe.Type {
+	case "exec":
+		return self.execProbe(timeout)
+	case "http":
+		return self.httpProbe(timeout)
+	case "tcp":
+		return self.tcpProbe(timeout)
+	default:
+		return errors.New("Unknown probe type")
+	}
+}
+
+// Run a command inside the container
+func (self *ProbeRunner) execProbe(timeout time.Duration) error {
+	errChan := make(chan error, 1)
+
+	// Run the command in background so that we can timeout
+	go func() {
+		_, err := self.containerCtx.ExecCmdInContainer(self.probe.Command)
+		errChan <- err
+	}()
+
+	select {
+	case err := <-errChan:
+		return err
+	case <-time.After(timeout):
+		return errors.New("Exec probe timed out")
+	}
+}
+
+// Connect to a TCP port inside the container
+func (self *ProbeRunner) tcpProbe(timeout time.Duration) error {
+	addr := net.JoinHostPort("127.0.0.1", strconv.Itoa(self.probe.Port))
+
+	conn, err := netutils.DialInNetns(self.containerCtx.GetContainerPid(), "tcp", addr, timeout)
+	if err != nil {
+		return err
+	}
+
+	return conn.Close()
+}
+
+// Perform an HTTP GET inside the container
+func (self *ProbeRunner) httpProbe(timeout time.Duration) error {
+	contPid := self.containerCtx.GetContainerPid()
+	url := "http://" + net.JoinHostPort("127.0.0.1", strconv.Itoa(self.probe.Port)) + self.probe.Path
+
+	// HTTP client that dials from container's namespace
+	client := http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			Dial: func(network, addr string) (net.Conn, error) {
+				return netutils.DialInNetns(contPid, network, addr, timeout)
+			},
+			DisableKeepAlives: true,
+		},
+	}
+
+	resp, err := client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// Any 2xx or 3xx response is success
+	if (resp.StatusCode < 200) || (resp.StatusCode >= 400) {
+		return fmt.Errorf("HTTP probe returned status %d", resp.StatusCode)
+	}
+
+	return nil
+}
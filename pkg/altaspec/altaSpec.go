@@ -34,6 +34,7 @@ type AltaNetSpec struct {
 	NetworkName string // Name of the network
 	VlanId      uint16 // Vlan Id
 	Vni         uint32 // Virtual network id(Vxlan VNI)
+	Encap       string // Network encap. vlan or vxlan
 }
 
 // Network endpoint definition
@@ -43,20 +44,64 @@ type AltaEndpoint struct {
 	IntfIpv4Addr    string // IP address for the interface
 	IntfIpv4Masklen int    // IP netmask length
 	Ipv4Gateway     string // default gateway
+	IntfIpv6Addr    string // IPv6 address for the interface. Empty if network is IPv4 only
+	IntfIpv6Masklen int    // IPv6 prefix length
+	Ipv6Gateway     string // IPv6 default gateway
+}
+
+// Health check probe for the container
+type AltaProbe struct {
+	Type             string   // Probe type [exec, http, tcp]
+	Command          []string // Command to run for exec probe
+	Path             string   // URL path for http probe
+	Port             int      // Container port for http and tcp probes
+	InitialDelay     int      // Seconds to wait after container start
+	Interval         int      // Seconds between probes
+	Timeout          int      // Seconds to wait for a probe to complete
+	FailureThreshold int      // Consecutive failures before declaring failure
+	SuccessThreshold int      // Consecutive successes before declaring success
+}
+
+// Run to completion job parameters
+type AltaJobSpec struct {
+	Completions  int  // Number of successful runs needed
+	Parallelism  int  // Number of altas to run in parallel
+	BackoffLimit *int // Number of failures before the job is failed. Defaults to 6 if not set
+}
+
+// Affinity rule selecting altas by their labels
+type AltaAffinity struct {
+	Selector map[string]string `json:"selector"` // Labels of the altas this rule applies to
+	Hard     bool              `json:"hard"`     // Hard rules must be met, soft rules are preferences
 }
 
 type AltaSchedPolicy struct {
-	SchedulerName string            // Name of the scheduler [leastUsed, binPack, random]
+	SchedulerName string            // Name of the scheduler [leastUsed, binPack, random, spread]
+	SpreadKey     string            // Node attribute to spread across for spread scheduler
 	RestartPolicy string            // restart policy [always, never, onFailure]
 	NumRestart    int               // number of times to restart
-	Filters       map[string]string // list of constraints
+	MaxRetries    int               // number of times to retry a failed operation
+	Priority      int               // Higher priority altas can preempt lower priority ones
+	PriorityClass string            // Named priority class the priority came from
+	Gang          string            // Gang this alta belongs to. Gang members are placed all or nothing
+	GangSize      int               // Number of altas in the gang
+	Filters       map[string]string // attribute filters. Value is a plain value or an expression like "in (a, b)"
+	Constraints   []string          // attribute constraint expressions like "cpu-mhz > 2000"
+	Affinity      []AltaAffinity    // place on nodes running matching altas
+	AntiAffinity  []AltaAffinity    // avoid nodes running matching altas
 	Resources     []Resource        // list of resources requested
+
+	AvoidNodes     []string // Nodes to avoid when placing the alta, e.g. while migrating
+	TargetNode     string   // Only node to place the alta on, e.g. planned migration target
+	MaxUnavailable int      // Max instances of the service that can be disrupted at a time
 }
 
 // Specifications for a Alta container
 type AltaSpec struct {
 	AltaId      string          // Unique identifier for the container
 	AltaName    string          // Optional Unique name for the container
+	Kind        string          // Kind of alta [service, job]
+	JobName     string          // Job this alta belongs to
 	NumCpu      uint32          // Number of CPUs cores
 	CpuPerc     int64           // CPU percentage on eahc core
 	Memory      int64           // Memory in MBs
@@ -69,8 +114,15 @@ type AltaSpec struct {
 	PortMapList []string        // Port mapping(for externally visible ports)
 	SchedPolicy AltaSchedPolicy // Scheduler policy
 
+	Labels map[string]string // Labels identifying the alta, e.g. its service
+
+	AllocatedResources []AllocatedResource // Resources scheduler allocated on the node
+
 	Volumes   []AltaVolumeBind // Volumes to be mounted
 	Endpoints []AltaEndpoint   // Network endpoints to be created
+
+	LivenessProbe  *AltaProbe // Restart the container when this fails
+	ReadinessProbe *AltaProbe // Container is ready to serve when this succeeds
 }
 
 // Resource available or consumed
@@ -80,6 +132,13 @@ type Resource struct {
 	NumRsrc  float64 // number of resources provided or consumed
 }
 
+// Resource allocated to an alta
+type AllocatedResource struct {
+	Type        string   // Resource type
+	NumRsrc     float64  // Number of resources allocated
+	RsrcIndexes []uint64 // for descrete resources, indexes allocated
+}
+
 // Slave node information
 type NodeSpec struct {
 	HostName   string            // Name of the host
@@ -93,10 +152,39 @@ type ReqSuccess struct {
 }
 
 type AltaContext struct {
-	AltaId      string
-	ContainerId string
+	AltaId         string
+	ContainerId    string
+	LivenessFailed bool // Liveness probe declared the container dead
+	Ready          bool // Readiness probe succeeded
+	Running        bool // Container is running
+	ExitCode       int  // Exit code if the container exited
+	Owned          bool // Container was created by symphony
+}
+
+// Request to execute a command in an alta container
+type AltaExecReq struct {
+	Command     []string // Command and its arguments
+	Interactive bool     // Attach stdin/stdout over an upgraded connection
+	Tty         bool     // Allocate a tty for interactive sessions
 }
 
+// Result of a one-shot command execution
+type AltaExecResp struct {
+	Stdout   string // Standard output of the command
+	Stderr   string // Standard error of the command
+	ExitCode int    // Exit code of the command
+}
+
+// Docker label identifying containers owned by symphony
+const AltaIdLabel = "symphony.altaId"
+
+// Labels identifying the service an alta belongs to
+const (
+	TenantLabel  = "symphony.tenant"
+	AppLabel     = "symphony.app"
+	ServiceLabel = "symphony.service"
+)
+
 // Docker compose options
 type DockerCompose struct {
 	Image          string `json:"image"`
@@ -140,4 +228,41 @@ type AltaConfig struct {
 	Networks    []string         `json:"network"`     // List of networks to join
 	Environment []string         `json:"environment"` // Optional environment variable
 	Volumes     []AltaVolumeBind `json:"volumes"`     // Volumes to mount
+
+	RestartPolicy string `json:"restartPolicy"` // restart policy [always, never, onFailure]
+	NumRestart    int    `json:"numRestart"`    // max number of restarts. 0 is unlimited
+	MaxRetries    int    `json:"maxRetries"`    // max retries for failed operations
+
+	Scheduler string            `json:"scheduler"` // Scheduler policy [leastUsed, binPack, random, spread]
+	SpreadKey string            `json:"spreadKey"` // Node attribute to spread across. Defaults to zone
+	Labels    map[string]string `json:"labels"`    // Labels identifying the alta
+
+	Priority      int    `json:"priority"`      // Scheduling priority. Higher priority altas can preempt lower ones
+	PriorityClass string `json:"priorityClass"` // Named priority [critical, production, default, dev]
+
+	Gang     string `json:"gang"`     // Place all altas of the gang or none of them
+	GangSize int    `json:"gangSize"` // Number of altas in the gang
+
+	MaxUnavailable int `json:"maxUnavailable"` // Instances of the service rebalancer can disrupt at a time. Defaults to 1
+
+	Resources    []Resource     `json:"resources"`    // Additional resources like licenses or ports
+	Constraints  []string       `json:"constraints"`  // Node attribute constraints, e.g. "zone in (a, b)"
+	Affinity     []AltaAffinity `json:"affinity"`     // Co-locate with altas matching these rules
+	AntiAffinity []AltaAffinity `json:"antiAffinity"` // Avoid nodes with altas matching these rules
+
+	Kind string      `json:"kind"` // Kind of alta [service, job]. Defaults to service
+	Job  AltaJobSpec `json:"job"`  // Job parameters for job kind
+
+	LivenessProbe  *AltaProbe `json:"livenessProbe"`  // Optional liveness check
+	ReadinessProbe *AltaProbe `json:"readinessProbe"` // Optional readiness check
+}
+
+// Cron job configuration. Creates a job from the template on each schedule
+type CronJobConfig struct {
+	Name              string     `json:"name"`              // Name of the cron job
+	Schedule          string     `json:"schedule"`          // Cron expression
+	ConcurrencyPolicy string     `json:"concurrencyPolicy"` // [allow, forbid, replace]. Defaults to allow
+	Suspend           bool       `json:"suspend"`           // Dont create new jobs
+	HistoryLimit      int        `json:"historyLimit"`      // Number of past runs to keep
+	JobTemplate       AltaConfig `json:"jobTemplate"`       // Template for the jobs
 }
@@ -0,0 +1,362 @@
+package ipam
+
+// This file implements IP address management for a subnet. A pool maps the
+// usable addresses of a subnet to a dense range of indexes so that a discrete
+// allocator, such as rsrcMgr, can hand them out
+
+import (
+	"errors"
+	"math"
+	"math/big"
+	"net"
+	"sort"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// Max number of addresses managed by a pool. Bigger subnets are capped
+var MaxPoolSize uint64 = 1 << 20
+
+// Address management config of a subnet
+type PoolSpec struct {
+	Subnet   string   // Subnet in CIDR notation
+	Gateway  string   // Gateway address. Defaults to first address in the subnet
+	Reserved []string // Addresses reserved for infrastructure. Must be inside the subnet
+	Excluded []string // Addresses that are never used, eg. managed by external DHCP
+	Slaac    bool     // Derive addresses from mac address. IPv6 /64 subnets only
+}
+
+// Address pool of a subnet
+type Pool struct {
+	Spec    PoolSpec   // Config of the pool
+	Subnet  net.IPNet  // Subnet of the pool
+	Gateway net.IP     // Gateway address
+	skip    []offRange // Sorted ranges of addresses that are not allocated
+	size    uint64     // Number of addresses in the pool
+}
+
+// Range of addresses as offsets from start of the subnet
+type offRange struct {
+	start uint64
+	end   uint64 // inclusive
+}
+
+// Create an address pool from its config
+func NewPool(spec PoolSpec) (*Pool, error) {
+	_, subnet, err := net.ParseCIDR(spec.Subnet)
+	if err != nil {
+		log.Errorf("Invalid subnet %s. Err: %v", spec.Subnet, err)
+		return nil, errors.New("Invalid subnet " + spec.Subnet)
+	}
+
+	pool := new(Pool)
+	pool.Spec = spec
+	pool.Subnet = *subnet
+
+	isV4 := (subnet.IP.To4() != nil)
+	total := pool.numAddrs()
+
+	// SLAAC needs 64 bits for interface id
+	if spec.Slaac && (isV4 || (pool.PrefixLen() != 64)) {
+		log.Errorf("SLAAC needs an IPv6 /64 subnet. Got %s", spec.Subnet)
+		return nil, errors.New("SLAAC needs an IPv6 /64 subnet")
+	}
+
+	// Network address is never used. Neither is broadcast address in IPv4
+	pool.skip = append(pool.skip, offRange{0, 0})
+	if isV4 && (total > 2) {
+		pool.skip = append(pool.skip, offRange{total - 1, total - 1})
+	}
+
+	// Excluded ranges are clipped to the subnet
+	var excluded []offRange
+	for _, rangeStr := range spec.Excluded {
+		excl, err := pool.parseRange(rangeStr, true)
+		if err != nil {
+			return nil, err
+		}
+		if excl != nil {
+			excluded = append(excluded, *excl)
+		}
+	}
+
+	// Reserved ranges must be inside the subnet
+	for _, rangeStr := range spec.Reserved {
+		rsvd, err := pool.parseRange(rangeStr, false)
+		if err != nil {
+			return nil, err
+		}
+		pool.skip = append(pool.skip, *rsvd)
+	}
+
+	// Pick the gateway
+	var gwOffset uint64 = 1
+	if spec.Gateway != "" {
+		gwIP := net.ParseIP(spec.Gateway)
+		if gwIP == nil {
+			log.Errorf("Invalid gateway %s", spec.Gateway)
+			return nil, errors.New("Invalid gateway " + spec.Gateway)
+		}
+		offset, err := pool.ipOffset(gwIP)
+		if err != nil {
+			log.Errorf("Gateway %s is not in subnet %s", spec.Gateway, spec.Subnet)
+			return nil, errors.New("Gateway is not in the subnet")
+		}
+		if (offset == 0) || (isV4 && (total > 2) && (offset == total-1)) {
+			log.Errorf("Gateway %s is not a host address in %s", spec.Gateway, spec.Subnet)
+			return nil, errors.New("Gateway is not a host address")
+		}
+		gwOffset = offset
+	}
+	if gwOffset >= total {
+		log.Errorf("Subnet %s has no room for a gateway", spec.Subnet)
+		return nil, errors.New("Subnet is too small")
+	}
+	if inRanges(excluded, gwOffset) {
+		log.Errorf("Gateway of %s is in an excluded range", spec.Subnet)
+		return nil, errors.New("Gateway is in an excluded range")
+	}
+	pool.Gateway = addOffset(subnet.IP, gwOffset)
+	pool.skip = append(pool.skip, offRange{gwOffset, gwOffset})
+	pool.skip = append(pool.skip, excluded...)
+
+	// Sort and merge the skipped ranges
+	pool.skip = mergeRanges(pool.skip)
+
+	// Size the pool from whats left
+	var numSkipped uint64
+	for _, skip := range pool.skip {
+		numSkipped += skip.end - skip.start + 1
+	}
+	if numSkipped >= total {
+		log.Errorf("Subnet %s has no usable addresses", spec.Subnet)
+		return nil, errors.New("Subnet has no usable addresses")
+	}
+	pool.size = total - numSkipped
+	if pool.size > MaxPoolSize {
+		pool.size = MaxPoolSize
+	}
+
+	return pool, nil
+}
+
+// Number of addresses in the pool
+func (self *Pool) Size() uint64 {
+	return self.size
+}
+
+// Prefix length of the subnet
+func (self *Pool) PrefixLen() int {
+	ones, _ := self.Subnet.Mask.Size()
+	return ones
+}
+
+// Return the address at an index of the pool
+func (self *Pool) IndexToIP(index uint64) (net.IP, error) {
+	if index >= self.size {
+		return nil, errors.New("Index out of range")
+	}
+
+	// Step over the skipped ranges before the address
+	offset := index
+	for _, skip := range self.skip {
+		if skip.start > offset {
+			break
+		}
+		offset += skip.end - skip.start + 1
+	}
+
+	return addOffset(self.Subnet.IP, offset), nil
+}
+
+// Return the index of an address in the pool
+func (self *Pool) IPToIndex(ip net.IP) (uint64, error) {
+	offset, err := self.ipOffset(ip)
+	if err != nil {
+		return 0, err
+	}
+
+	// Subtract the skipped ranges before the address
+	index := offset
+	for _, skip := range self.skip {
+		if skip.start > offset {
+			break
+		}
+		if skip.end >= offset {
+			return 0, errors.New("Address is not allocatable")
+		}
+		index -= skip.end - skip.start + 1
+	}
+
+	if index >= self.size {
+		return 0, errors.New("Address is not in the pool")
+	}
+
+	return index, nil
+}
+
+// Return the SLAAC address of a mac address. Interface id is the modified
+// EUI-64 form of the mac address
+func (self *Pool) SlaacAddr(macAddr net.HardwareAddr) (net.IP, error) {
+	if !self.Spec.Slaac {
+		return nil, errors.New("Pool does not use SLAAC")
+	}
+	if len(macAddr) != 6 {
+		return nil, errors.New("Invalid mac address " + macAddr.String())
+	}
+
+	ip := make(net.IP, net.IPv6len)
+	copy(ip, self.Subnet.IP.To16()[:8])
+
+	// Insert ff:fe in the middle of mac and flip the universal/local bit
+	ip[8] = macAddr[0] ^ 0x02
+	ip[9] = macAddr[1]
+	ip[10] = macAddr[2]
+	ip[11] = 0xff
+	ip[12] = 0xfe
+	ip[13] = macAddr[3]
+	ip[14] = macAddr[4]
+	ip[15] = macAddr[5]
+
+	return ip, nil
+}
+
+// Check if two subnets overlap. Subnets of different families never overlap
+func Overlaps(subnet1, subnet2 *net.IPNet) bool {
+	if (subnet1.IP == nil) || (subnet2.IP == nil) {
+		return false
+	}
+
+	return subnet1.Contains(subnet2.IP) || subnet2.Contains(subnet1.IP)
+}
+
+// Number of addresses in the subnet, saturated at max uint64
+func (self *Pool) numAddrs() uint64 {
+	ones, bits := self.Subnet.Mask.Size()
+	if bits-ones >= 64 {
+		return math.MaxUint64
+	}
+
+	return uint64(1) << uint(bits-ones)
+}
+
+// Offset of an address from start of the subnet
+func (self *Pool) ipOffset(ip net.IP) (uint64, error) {
+	if !self.Subnet.Contains(ip) {
+		return 0, errors.New("Address " + ip.String() + " is not in the subnet")
+	}
+
+	offset := new(big.Int).Sub(ipToInt(ip), ipToInt(self.Subnet.IP))
+	if !offset.IsUint64() {
+		return math.MaxUint64, nil
+	}
+
+	return offset.Uint64(), nil
+}
+
+// Parse a range of the form "ip" or "startIp-endIp". Returns nil if range
+// is outside the subnet and clipping is allowed
+func (self *Pool) parseRange(rangeStr string, clip bool) (*offRange, error) {
+	addrs := strings.SplitN(rangeStr, "-", 2)
+	startIP := net.ParseIP(strings.TrimSpace(addrs[0]))
+	endIP := startIP
+	if len(addrs) == 2 {
+		endIP = net.ParseIP(strings.TrimSpace(addrs[1]))
+	}
+	if (startIP == nil) || (endIP == nil) ||
+		((startIP.To4() == nil) != (self.Subnet.IP.To4() == nil)) ||
+		((endIP.To4() == nil) != (self.Subnet.IP.To4() == nil)) ||
+		(ipToInt(startIP).Cmp(ipToInt(endIP)) > 0) {
+		log.Errorf("Invalid address range %s", rangeStr)
+		return nil, errors.New("Invalid address range " + rangeStr)
+	}
+
+	startIn := self.Subnet.Contains(startIP)
+	endIn := self.Subnet.Contains(endIP)
+	if !clip && (!startIn || !endIn) {
+		log.Errorf("Range %s is not in subnet %s", rangeStr, self.Spec.Subnet)
+		return nil, errors.New("Range " + rangeStr + " is not in the subnet")
+	}
+
+	// Clip the range to the subnet
+	subnetStart := ipToInt(self.Subnet.IP)
+	subnetEnd := new(big.Int).Add(subnetStart, new(big.Int).SetUint64(self.numAddrs()-1))
+	if (ipToInt(endIP).Cmp(subnetStart) < 0) || (ipToInt(startIP).Cmp(subnetEnd) > 0) {
+		return nil, nil
+	}
+
+	var rng offRange
+	if startIn {
+		rng.start, _ = self.ipOffset(startIP)
+	}
+	if endIn {
+		rng.end, _ = self.ipOffset(endIP)
+	} else {
+		rng.end = self.numAddrs() - 1
+	}
+
+	return &rng, nil
+}
+
+// Check if an offset is in any of the ranges
+func inRanges(ranges []offRange, offset uint64) bool {
+	for _, rng := range ranges {
+		if (offset >= rng.start) && (offset <= rng.end) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Sort the ranges and merge the overlapping ones
+func mergeRanges(ranges []offRange) []offRange {
+	sort.Sort(byStart(ranges))
+
+	var merged []offRange
+	for _, rng := range ranges {
+		last := len(merged) - 1
+		if (last >= 0) && (merged[last].end != math.MaxUint64) && (rng.start <= merged[last].end+1) {
+			if rng.end > merged[last].end {
+				merged[last].end = rng.end
+			}
+			continue
+		}
+		merged = append(merged, rng)
+	}
+
+	return merged
+}
+
+// Convert an address to an integer
+func ipToInt(ip net.IP) *big.Int {
+	if ip4 := ip.To4(); ip4 != nil {
+		return new(big.Int).SetBytes(ip4)
+	}
+
+	return new(big.Int).SetBytes(ip.To16())
+}
+
+// Return the address at an offset from base address
+func addOffset(base net.IP, offset uint64) net.IP {
+	addr := new(big.Int).Add(ipToInt(base), new(big.Int).SetUint64(offset))
+
+	// Pad it to the address length
+	ipLen := net.IPv6len
+	if base.To4() != nil {
+		ipLen = net.IPv4len
+	}
+	addrBytes := addr.Bytes()
+	ip := make(net.IP, ipLen)
+	copy(ip[ipLen-len(addrBytes):], addrBytes)
+
+	return ip
+}
+
+// Sort ranges by start offset
+type byStart []offRange
+
+func (s byStart) Len() int           { return len(s) }
+func (s byStart) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s byStart) Less(i, j int) bool { return s[i].start < s[j].start }
@@ -0,0 +1,199 @@
+package ipam
+
+import (
+	"net"
+	"testing"
+)
+
+// Allocate all addresses of a pool and check the mapping both ways
+func checkPool(t *testing.T, pool *Pool, expFirst, expLast string) {
+	seen := make(map[string]bool)
+	for index := uint64(0); index < pool.Size(); index++ {
+		ip, err := pool.IndexToIP(index)
+		if err != nil {
+			t.Fatalf("Error getting address %d. Err: %v", index, err)
+		}
+		if seen[ip.String()] {
+			t.Fatalf("Address %s returned twice", ip.String())
+		}
+		seen[ip.String()] = true
+
+		if ip.Equal(pool.Gateway) {
+			t.Errorf("Gateway %s is in the pool", ip.String())
+		}
+
+		revIndex, err := pool.IPToIndex(ip)
+		if (err != nil) || (revIndex != index) {
+			t.Errorf("Address %s maps to index %d, expected %d. Err: %v", ip.String(), revIndex, index, err)
+		}
+	}
+
+	first, _ := pool.IndexToIP(0)
+	last, _ := pool.IndexToIP(pool.Size() - 1)
+	if (first.String() != expFirst) || (last.String() != expLast) {
+		t.Errorf("Pool range %s-%s, expected %s-%s", first, last, expFirst, expLast)
+	}
+	if _, err := pool.IndexToIP(pool.Size()); err == nil {
+		t.Errorf("Index past the pool size was accepted")
+	}
+}
+
+func TestDefaultGateway(t *testing.T) {
+	pool, err := NewPool(PoolSpec{Subnet: "10.200.1.0/24"})
+	if err != nil {
+		t.Fatalf("Error creating pool. Err: %v", err)
+	}
+
+	// Same as the legacy scheme: gw at .1 and 253 addresses from .2
+	if (pool.Gateway.String() != "10.200.1.1") || (pool.Size() != 253) || (pool.PrefixLen() != 24) {
+		t.Errorf("Unexpected pool: gw %s, size %d", pool.Gateway, pool.Size())
+	}
+	checkPool(t, pool, "10.200.1.2", "10.200.1.254")
+}
+
+func TestPrefixSizing(t *testing.T) {
+	pool, err := NewPool(PoolSpec{Subnet: "10.1.0.0/16"})
+	if err != nil {
+		t.Fatalf("Error creating pool. Err: %v", err)
+	}
+	if pool.Size() != 65533 {
+		t.Errorf("Pool of /16 has %d addresses", pool.Size())
+	}
+	checkPool(t, pool, "10.1.0.2", "10.1.255.254")
+
+	pool, err = NewPool(PoolSpec{Subnet: "192.168.1.0/29"})
+	if err != nil {
+		t.Fatalf("Error creating pool. Err: %v", err)
+	}
+	if pool.Size() != 5 {
+		t.Errorf("Pool of /29 has %d addresses", pool.Size())
+	}
+
+	// Big subnets are capped
+	pool, err = NewPool(PoolSpec{Subnet: "10.0.0.0/8"})
+	if err != nil {
+		t.Fatalf("Error creating pool. Err: %v", err)
+	}
+	if pool.Size() != MaxPoolSize {
+		t.Errorf("Pool of /8 has %d addresses", pool.Size())
+	}
+
+	// Subnets without usable addresses are rejected
+	for _, subnet := range []string{"10.1.1.1/32", "10.1.1.0/31", "10.1.1.0/33", "foo"} {
+		if _, err := NewPool(PoolSpec{Subnet: subnet}); err == nil {
+			t.Errorf("Subnet %s was accepted", subnet)
+		}
+	}
+}
+
+func TestGatewayAndRanges(t *testing.T) {
+	pool, err := NewPool(PoolSpec{
+		Subnet:   "192.168.1.0/24",
+		Gateway:  "192.168.1.254",
+		Reserved: []string{"192.168.1.1-192.168.1.9"},
+		Excluded: []string{"192.168.1.100-192.168.1.199", "192.168.0.250-192.168.1.10", "10.0.0.1"},
+	})
+	if err != nil {
+		t.Fatalf("Error creating pool. Err: %v", err)
+	}
+
+	// 254 hosts - gateway - .1 to .10 - .100 to .199
+	if (pool.Gateway.String() != "192.168.1.254") || (pool.Size() != 143) {
+		t.Errorf("Unexpected pool: gw %s, size %d", pool.Gateway, pool.Size())
+	}
+	checkPool(t, pool, "192.168.1.11", "192.168.1.253")
+
+	ip, _ := pool.IndexToIP(89)
+	if ip.String() != "192.168.1.200" {
+		t.Errorf("Address 89 is %s, expected 192.168.1.200", ip)
+	}
+	for _, addr := range []string{"192.168.1.5", "192.168.1.150", "192.168.1.254", "192.168.1.255", "10.0.0.1"} {
+		if _, err := pool.IPToIndex(net.ParseIP(addr)); err == nil {
+			t.Errorf("Address %s is allocatable", addr)
+		}
+	}
+
+	// Bad gateways and ranges
+	badSpecs := []PoolSpec{
+		{Subnet: "192.168.1.0/24", Gateway: "192.168.2.1"},
+		{Subnet: "192.168.1.0/24", Gateway: "192.168.1.0"},
+		{Subnet: "192.168.1.0/24", Gateway: "192.168.1.255"},
+		{Subnet: "192.168.1.0/24", Gateway: "192.168.1.50", Excluded: []string{"192.168.1.40-192.168.1.60"}},
+		{Subnet: "192.168.1.0/24", Reserved: []string{"192.168.1.250-192.168.2.5"}},
+		{Subnet: "192.168.1.0/24", Reserved: []string{"192.168.1.20-192.168.1.10"}},
+		{Subnet: "192.168.1.0/24", Excluded: []string{"192.168.1.2-192.168.1.255"}},
+	}
+	for _, spec := range badSpecs {
+		if _, err := NewPool(spec); err == nil {
+			t.Errorf("Pool spec %+v was accepted", spec)
+		}
+	}
+}
+
+func TestOverlaps(t *testing.T) {
+	_, net1, _ := net.ParseCIDR("10.1.0.0/16")
+	_, net2, _ := net.ParseCIDR("10.1.5.0/24")
+	_, net3, _ := net.ParseCIDR("10.2.0.0/16")
+
+	if !Overlaps(net1, net2) || !Overlaps(net2, net1) {
+		t.Errorf("%s and %s should overlap", net1, net2)
+	}
+	if Overlaps(net1, net3) || Overlaps(net2, net3) {
+		t.Errorf("%s should not overlap", net3)
+	}
+}
+
+func TestIPv6Pool(t *testing.T) {
+	// Sequential allocation from a /120
+	pool, err := NewPool(PoolSpec{Subnet: "fd00:1::/120", Excluded: []string{"fd00:1::2-fd00:1::f"}})
+	if err != nil {
+		t.Fatalf("Error creating pool. Err: %v", err)
+	}
+
+	// There is no broadcast address in IPv6
+	if (pool.Gateway.String() != "fd00:1::1") || (pool.Size() != 240) || (pool.PrefixLen() != 120) {
+		t.Errorf("Unexpected pool: gw %s, size %d", pool.Gateway, pool.Size())
+	}
+	checkPool(t, pool, "fd00:1::10", "fd00:1::ff")
+
+	// Big prefixes are capped
+	pool, err = NewPool(PoolSpec{Subnet: "fd00:2::/64", Gateway: "fd00:2::fffe"})
+	if err != nil {
+		t.Fatalf("Error creating pool. Err: %v", err)
+	}
+	if pool.Size() != MaxPoolSize {
+		t.Errorf("Pool of /64 has %d addresses", pool.Size())
+	}
+	ip, _ := pool.IndexToIP(0)
+	if ip.String() != "fd00:2::1" {
+		t.Errorf("First address is %s", ip)
+	}
+
+	// SLAAC
+	pool, err = NewPool(PoolSpec{Subnet: "fd00:3::/64", Slaac: true})
+	if err != nil {
+		t.Fatalf("Error creating pool. Err: %v", err)
+	}
+	mac, _ := net.ParseMAC("02:02:02:00:01:02")
+	ip, err = pool.SlaacAddr(mac)
+	if (err != nil) || (ip.String() != "fd00:3::2:2ff:fe00:102") {
+		t.Errorf("SLAAC address is %s. Err: %v", ip, err)
+	}
+
+	// SLAAC needs a v6 /64
+	for _, subnet := range []string{"fd00:4::/96", "10.1.1.0/24"} {
+		if _, err := NewPool(PoolSpec{Subnet: subnet, Slaac: true}); err == nil {
+			t.Errorf("SLAAC on %s was accepted", subnet)
+		}
+	}
+
+	// Mixing families
+	_, net4, _ := net.ParseCIDR("10.0.0.0/8")
+	_, net6, _ := net.ParseCIDR("::/0")
+	if Overlaps(net4, net6) {
+		t.Errorf("%s and %s should not overlap", net4, net6)
+	}
+	if _, err := NewPool(PoolSpec{Subnet: "fd00:5::/64", Gateway: "10.0.0.1"}); err == nil {
+		t.Errorf("IPv4 gateway on IPv6 subnet was accepted")
+	}
+}
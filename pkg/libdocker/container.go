@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
 	"strings"
 
 	log "github.com/Sirupsen/logrus"
@@ -34,6 +36,23 @@ type ContainerSpec struct {
 	ExposePorts       []string // Expose these ports(alternative to EXPOSE in Dockerfile)
 	PortMapList       []string // Port mapping from container port to host ports
 	NetworkMode       string   // Network mode to be used for inheriting other container's network namespace
+
+	Labels map[string]string // Labels to attach to the container
+}
+
+// Result of a command executed in a container
+type ExecResult struct {
+	Stdout   string // Standard output of the command
+	Stderr   string // Standard error of the command
+	ExitCode int    // Exit code of the command
+}
+
+// Summary information about a container
+type ContainerInfo struct {
+	Id       string            // Docker container id
+	Labels   map[string]string // Labels attached to the container
+	Running  bool              // Is the container running
+	ExitCode int               // Exit code of the primary process if it exited
 }
 
 // Convert CPU percentage unit to CPU shares docker uses.
@@ -90,6 +109,7 @@ func CreateContainer(cSpec *ContainerSpec) (*ContainerCtx, error) {
 			WorkingDir:   cSpec.WorkingDir,
 			Entrypoint:   cSpec.Command,
 			Cmd:          cSpec.Args,
+			Labels:       cSpec.Labels,
 
 			AttachStdin:  false,
 			AttachStdout: false,
@@ -213,43 +233,94 @@ func (self *ContainerCtx) RemoveContainer() error {
 }
 
 // Execute a command in a container's context
+// Returns an error if the command could not be run or exited with non-zero code
 func (self *ContainerCtx) ExecCmdInContainer(cmds []string) (*bytes.Buffer, error) {
+	var buf bytes.Buffer
+
+	// Execute the command with stdout and stderr combined
+	exitCode, err := self.execInContainer(cmds, false, nil, &buf, &buf)
+	if err != nil {
+		return &buf, err
+	}
+
+	log.Debugf("Got Output: \n %s\n", buf.String())
+
+	if exitCode != 0 {
+		return &buf, fmt.Errorf("Command exited with code %d", exitCode)
+	}
+
+	return &buf, nil
+}
+
+// Execute a command in a container and return its output and exit code
+func (self *ContainerCtx) ExecCmd(cmds []string) (*ExecResult, error) {
+	var stdout, stderr bytes.Buffer
+
+	// Execute the command
+	exitCode, err := self.execInContainer(cmds, false, nil, &stdout, &stderr)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ExecResult{
+		Stdout:   stdout.String(),
+		Stderr:   stderr.String(),
+		ExitCode: exitCode,
+	}, nil
+}
+
+// Execute an interactive command in a container attaching stdin and stdout.
+// This blocks till the command exits and returns its exit code
+func (self *ContainerCtx) ExecInteractive(cmds []string, tty bool, stdin io.Reader,
+	stdout io.Writer) (int, error) {
+	return self.execInContainer(cmds, tty, stdin, stdout, stdout)
+}
+
+// Create an exec instance, run it and return the exit code
+func (self *ContainerCtx) execInContainer(cmds []string, tty bool, stdin io.Reader,
+	stdout, stderr io.Writer) (int, error) {
 	// Options for exec
 	execOpts := docker.CreateExecOptions{
 		Container:    self.DockerId,
 		Cmd:          cmds,
-		AttachStdin:  false,
+		AttachStdin:  (stdin != nil),
 		AttachStdout: true,
 		AttachStderr: true,
-		Tty:          false,
+		Tty:          tty,
 	}
 
 	// Create an exec context
 	execCtx, err := dockerClient.CreateExec(execOpts)
 	if err != nil {
 		log.Errorf("Failed to create exec Ctx for %s", self.DockerId)
-		return nil, err
+		return -1, err
 	}
 
 	// Options for start exec
-	var buf bytes.Buffer
 	startExecOpts := docker.StartExecOptions{
 		Detach:       false,
-		OutputStream: &buf,
+		Tty:          tty,
+		InputStream:  stdin,
+		OutputStream: stdout,
+		ErrorStream:  stderr,
+		RawTerminal:  tty,
 	}
 
 	// Execute the comands
-	dockerClient.StartExec(execCtx.ID, startExecOpts)
-
-	log.Infof("Got Output: \n %s\n", buf)
-
-	return &buf, nil
-}
+	err = dockerClient.StartExec(execCtx.ID, startExecOpts)
+	if err != nil {
+		log.Errorf("Failed to exec %v in %s. Err: %v", cmds, self.DockerId, err)
+		return -1, err
+	}
 
-// Get stdout/stderr output of container (i.e. of primary process in container)
-// TODO:
-func (self *ContainerCtx) GetContainerLog() {
+	// Get the exit code
+	execInfo, err := dockerClient.InspectExec(execCtx.ID)
+	if err != nil {
+		log.Errorf("Failed to inspect exec %s in %s. Err: %v", execCtx.ID, self.DockerId, err)
+		return -1, err
+	}
 
+	return execInfo.ExitCode, nil
 }
 
 func (self *ContainerCtx) GetContainerPid() int {
@@ -295,3 +366,47 @@ func GetAllContainers() ([]string, error) {
 
 	return containerList, nil
 }
+
+// Get info about all containers(running or exited) on this system
+func GetContainerInfoList() ([]ContainerInfo, error) {
+	// Get a list of containers
+	containers, err := dockerClient.ListContainers(docker.ListContainersOptions{All: true})
+	if err != nil {
+		log.Errorf("Error getting a list of containers. Err: %v", err)
+		return nil, err
+	}
+
+	var containerList []ContainerInfo
+	for _, cntr := range containers {
+		// Inspect the container to get labels and state
+		containerInfo, err := dockerClient.InspectContainer(cntr.ID)
+		if err != nil {
+			log.Errorf("Error getting containerInfo for %s. Err: %v", cntr.ID, err)
+			continue
+		}
+
+		cinfo := ContainerInfo{
+			Id:       cntr.ID,
+			Running:  containerInfo.State.Running,
+			ExitCode: containerInfo.State.ExitCode,
+		}
+		if containerInfo.Config != nil {
+			cinfo.Labels = containerInfo.Config.Labels
+		}
+
+		containerList = append(containerList, cinfo)
+	}
+
+	log.Debugf("Got container info list: %+v", containerList)
+
+	return containerList, nil
+}
+
+// Get the labels attached to the container
+func (self *ContainerCtx) GetContainerLabels() map[string]string {
+	if (self.dockerInfo == nil) || (self.dockerInfo.Config == nil) {
+		return nil
+	}
+
+	return self.dockerInfo.Config.Labels
+}
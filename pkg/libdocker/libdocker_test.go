@@ -1,7 +1,9 @@
 package libdocker
 
 import (
+	"bytes"
 	"testing"
+	"time"
 
 	log "github.com/Sirupsen/logrus"
 )
@@ -72,3 +74,21 @@ func TestContainer(t *testing.T) {
 		t.Errorf("Failed to remove the container. Error %v", err)
 	}
 }
+
+// Test filtering log lines by time
+func TestLogFilter(t *testing.T) {
+	var buf bytes.Buffer
+	since, _ := time.Parse(time.RFC3339Nano, "2015-06-01T10:00:00Z")
+	filter := logFilterWriter{
+		writer: &buf,
+		since:  since,
+	}
+
+	// Write lines in partial chunks
+	filter.Write([]byte("2015-06-01T09:59:59.5Z old line\n2015-06-01T10:00:01Z new "))
+	filter.Write([]byte("line\n2015-06-01T10:00:02Z partial"))
+
+	if buf.String() != "new line\n" {
+		t.Errorf("Log filter returned unexpected output: %q", buf.String())
+	}
+}
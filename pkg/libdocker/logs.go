@@ -0,0 +1,120 @@
+package libdocker
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	docker "github.com/fsouza/go-dockerclient"
+)
+
+// Options for retrieving container logs
+type LogOptions struct {
+	Tail       string    // Number of lines from the end of the log, "all" for everything
+	Since      time.Time // Only return log lines after this time
+	Timestamps bool      // Prefix each line with its timestamp
+	Follow     bool      // Keep streaming new log lines till the container exits
+}
+
+// Writer that filters log lines older than a given time.
+// Docker prefixes each line with RFC3339Nano timestamp followed by a space
+type logFilterWriter struct {
+	writer     io.Writer    // Where to write filtered lines
+	since      time.Time    // Drop lines before this time
+	timestamps bool         // Keep the timestamp prefix
+	buf        bytes.Buffer // Partial line
+	mutex      sync.Mutex   // Lock for the partial line
+}
+
+// Write filters complete lines and buffers any partial line
+func (self *logFilterWriter) Write(p []byte) (int, error) {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	self.buf.Write(p)
+
+	// Process each complete line
+	for {
+		line, err := self.buf.ReadString('\n')
+		if err != nil {
+			// Put back the partial line
+			self.buf.Reset()
+			self.buf.WriteString(line)
+			break
+		}
+
+		err = self.writeLine(line)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return len(p), nil
+}
+
+// Write a single log line if its newer than since time
+func (self *logFilterWriter) writeLine(line string) error {
+	// Split the timestamp
+	parts := strings.SplitN(line, " ", 2)
+	if len(parts) == 2 {
+		ts, err := time.Parse(time.RFC3339Nano, parts[0])
+		if err == nil {
+			if ts.Before(self.since) {
+				return nil
+			}
+			if !self.timestamps {
+				line = parts[1]
+			}
+		}
+	}
+
+	_, err := io.WriteString(self.writer, line)
+	return err
+}
+
+// Get stdout/stderr output of container (i.e. of primary process in container)
+// This blocks till all logs are written or container exits in follow mode
+func (self *ContainerCtx) GetContainerLog(opts LogOptions, stdout, stderr io.Writer) error {
+	logOpts := docker.LogsOptions{
+		Container:    self.DockerId,
+		OutputStream: stdout,
+		ErrorStream:  stderr,
+		Follow:       opts.Follow,
+		Stdout:       true,
+		Stderr:       true,
+		Timestamps:   opts.Timestamps,
+		Tail:         opts.Tail,
+		RawTerminal:  false,
+	}
+
+	// Docker API we use does not support since. Filter it ourselves
+	if !opts.Since.IsZero() {
+		logOpts.Timestamps = true
+		logOpts.OutputStream = &logFilterWriter{
+			writer:     stdout,
+			since:      opts.Since,
+			timestamps: opts.Timestamps,
+		}
+		logOpts.ErrorStream = &logFilterWriter{
+			writer:     stderr,
+			since:      opts.Since,
+			timestamps: opts.Timestamps,
+		}
+	}
+
+	if logOpts.Tail == "" {
+		logOpts.Tail = "all"
+	}
+
+	// Get the logs
+	err := dockerClient.Logs(logOpts)
+	if err != nil {
+		log.Errorf("Error getting logs for container %s. Err: %v", self.DockerId, err)
+		return err
+	}
+
+	return nil
+}
@@ -1,6 +1,7 @@
 package libfsm
 
 import (
+	"errors"
 	"strings"
 
 	log "github.com/Sirupsen/logrus"
@@ -13,6 +14,9 @@ import (
 //   automatically to a distributed datastore like etcd/consul
 // - Events are queued to FSM using the event channel and processed in order
 
+// Error returned when event is not valid in current state
+var ErrInvalidEvent = errors.New("Invalid event in current state")
+
 // Main FSM structure
 type Fsm struct {
 	transitions *FsmTable // FSM transition table
@@ -48,8 +52,21 @@ func NewFsm(fsmTable *FsmTable, initState string) *Fsm {
 	return fsm
 }
 
+// Check if an event is valid in FSM's current state
+func (self *Fsm) IsValidEvent(eventName string) bool {
+	// find the <currState,event> pair in the transition table
+	for _, trans := range *self.transitions {
+		if (trans.CurrState == self.FsmState) && (trans.EventName == eventName) {
+			return true
+		}
+	}
+
+	return false
+}
+
 // Handle a new event for the fsm
-func (self *Fsm) FsmEvent(event Event) {
+// Returns the error from the callback or ErrInvalidEvent
+func (self *Fsm) FsmEvent(event Event) error {
 	// supress periodic logging..
 	if !strings.Contains(event.EventName, "tick") {
 		log.Infof("Processing event %s in state %s", event.EventName, self.FsmState)
@@ -61,14 +78,14 @@ func (self *Fsm) FsmEvent(event Event) {
 			if err != nil {
 				log.Errorf("Processing event %s failed in state %s", event.EventName, self.FsmState)
 
-				return
+				return err
 			} else {
 				if self.FsmState != trans.NewState {
 					log.Infof("Transitioning to state %s", trans.NewState)
 					self.FsmState = trans.NewState
 				}
 
-				return
+				return nil
 			}
 		}
 	}
@@ -76,5 +93,5 @@ func (self *Fsm) FsmEvent(event Event) {
 	// If we reached here, we did not find a valid transition
 	log.Errorf("Invalid event %s in state %s", event.EventName, self.FsmState)
 
-	return
+	return ErrInvalidEvent
 }
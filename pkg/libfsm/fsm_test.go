@@ -70,3 +70,48 @@ func TestFsmTransition(t *testing.T) {
 		t.Errorf("FSM event failed")
 	}
 }
+
+// Test event validation in current state
+func TestFsmValidEvent(t *testing.T) {
+	// Create fsm
+	testFsm := NewTestFsm("created state")
+
+	// stop is not allowed in created state
+	if testFsm.Fsm.IsValidEvent("stop") {
+		t.Errorf("stop event allowed in created state")
+	}
+	if !testFsm.Fsm.IsValidEvent("start") {
+		t.Errorf("start event not allowed in created state")
+	}
+
+	// Move to started state
+	testFsm.Fsm.FsmEvent(Event{"start", nil})
+
+	if testFsm.Fsm.IsValidEvent("start") {
+		t.Errorf("start event allowed in started state")
+	}
+	if !testFsm.Fsm.IsValidEvent("stop") {
+		t.Errorf("stop event not allowed in started state")
+	}
+}
+
+// Test errors returned by event processing
+func TestFsmEventError(t *testing.T) {
+	// Create fsm
+	testFsm := NewTestFsm("created state")
+
+	// stop is not allowed in created state
+	err := testFsm.Fsm.FsmEvent(Event{"stop", nil})
+	if err != ErrInvalidEvent {
+		t.Errorf("Invalid event did not return error. Err: %v", err)
+	}
+	if testFsm.Fsm.FsmState != "created" {
+		t.Errorf("Invalid event changed the state to %s", testFsm.Fsm.FsmState)
+	}
+
+	// start is allowed
+	err = testFsm.Fsm.FsmEvent(Event{"start", nil})
+	if err != nil {
+		t.Errorf("Valid event returned error. Err: %v", err)
+	}
+}
@@ -3,11 +3,13 @@ package netutils
 import (
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"net"
 	"os"
 	"path"
 	"runtime"
 	"strconv"
+	"time"
 
 	log "github.com/Sirupsen/logrus"
 	"github.com/vishvananda/netlink"
@@ -72,6 +74,11 @@ type NetnsIntfIdentify struct {
 	IPAddr     string
 	NetmaskLen int
 	DefaultGw  string
+
+	// IPv6 identity. Empty for IPv4 only interfaces
+	IPv6Addr       string
+	IPv6NetmaskLen int
+	DefaultGwV6    string
 }
 
 // Set Interface identity including its name, mac addr & ip addr within a network namespace
@@ -119,6 +126,20 @@ func SetNetnsIntfIdentity(nsPid int, portName string, identity NetnsIntfIdentify
 		return err
 	}
 
+	// Add IPv6 address on dual stack interfaces
+	if identity.IPv6Addr != "" {
+		if err = EnableInterfaceIpv6(newPortName); err != nil {
+			log.Errorf("Error enabling IPv6 on interface")
+			return err
+		}
+
+		ipv6AddrMask := identity.IPv6Addr + "/" + strconv.Itoa(identity.IPv6NetmaskLen)
+		if err = SetInterfaceIp(newPortName, ipv6AddrMask); err != nil {
+			log.Errorf("Error setting interface IPv6 address")
+			return err
+		}
+	}
+
 	if err = SetInterfaceMac(newPortName, identity.MacAddr); err != nil {
 		log.Errorf("Error setting interface Mac")
 		return err
@@ -135,6 +156,13 @@ func SetNetnsIntfIdentity(nsPid int, portName string, identity NetnsIntfIdentify
 		// return err
 	}
 
+	if identity.DefaultGwV6 != "" {
+		if err = SetDefaultGateway(identity.DefaultGwV6, newPortName); err != nil {
+			log.Errorf("Error setting IPv6 default GW")
+			// FIXME: same as IPv4, this fails if container has more than one intf
+		}
+	}
+
 	return nil
 }
 
@@ -188,7 +216,12 @@ func SetDefaultGateway(ip, ifaceName string) error {
 		return errors.New("Invalid gateway address")
 	}
 
-	_, dst, err := net.ParseCIDR("0.0.0.0/0")
+	// Default route of the gateway's address family
+	defaultDst := "0.0.0.0/0"
+	if gw.To4() == nil {
+		defaultDst = "::/0"
+	}
+	_, dst, err := net.ParseCIDR(defaultDst)
 	if err != nil {
 		return err
 	}
@@ -226,6 +259,24 @@ func SetInterfaceIp(name string, rawIp string) error {
 	return netlink.AddrAdd(iface, addr)
 }
 
+// Enable IPv6 on an interface. Container runtimes may disable it in the namespace.
+// Must be called from within the interface's network namespace
+func EnableInterfaceIpv6(name string) error {
+	for _, ifName := range []string{"all", name} {
+		sysctlFile := path.Join("/proc/sys/net/ipv6/conf", ifName, "disable_ipv6")
+		err := ioutil.WriteFile(sysctlFile, []byte("0"), 0644)
+		if err != nil {
+			// Kernel without IPv6 has no sysctl. Adding the address will fail anyway
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+	}
+
+	return nil
+}
+
 func SetMtu(name string, mtu int) error {
 	iface, err := netlink.LinkByName(name)
 	if err != nil {
@@ -233,3 +284,33 @@ func SetMtu(name string, mtu int) error {
 	}
 	return netlink.LinkSetMTU(iface, mtu)
 }
+
+// Dial a connection from within a container's network namespace
+func DialInNetns(nsPid int, network, address string, timeout time.Duration) (net.Conn, error) {
+	// Lock the OS Thread so we don't accidentally switch namespaces
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	origns, err := netns.Get()
+	if err != nil {
+		log.Errorf("Error getting current network namespace")
+		return nil, err
+	}
+	defer origns.Close()
+
+	targetns, err := netns.GetFromPid(nsPid)
+	if err != nil {
+		log.Errorf("Error getting network namespace for pid %d", nsPid)
+		return nil, err
+	}
+	defer targetns.Close()
+
+	if err = netns.Set(targetns); err != nil {
+		log.Errorf("Error switching network namespace")
+		return nil, err
+	}
+	defer netns.Set(origns)
+
+	// Socket is created in the target namespace and stays there
+	return net.DialTimeout(network, address, timeout)
+}
@@ -22,8 +22,13 @@ type OvsDriver struct {
 	ovsdbCache map[string]map[string]libovsdb.Row
 }
 
-// Create a new OVS driver
+// Create a new OVS driver for the default bridge
 func NewOvsDriver() *OvsDriver {
+	return NewOvsDriverForBridge("ovsbr0")
+}
+
+// Create a new OVS driver that manages ports on the named bridge
+func NewOvsDriverForBridge(bridgeName string) *OvsDriver {
 	ovsDriver := new(OvsDriver)
 
 	// connect to OVS
@@ -34,7 +39,7 @@ func NewOvsDriver() *OvsDriver {
 
 	// Setup state
 	ovsDriver.ovsClient = ovs
-	ovsDriver.OvsBridgeName = "ovsbr0"
+	ovsDriver.OvsBridgeName = bridgeName
 	ovsDriver.ovsdbCache = make(map[string]map[string]libovsdb.Row)
 
 	go func() {
@@ -49,10 +54,10 @@ func NewOvsDriver() *OvsDriver {
 	// HACK: sleep the main thread so that Cache can be populated
 	time.Sleep(1 * time.Second)
 
-	// Create the default bridge instance
+	// Create the bridge instance
 	err = ovsDriver.CreateBridge(ovsDriver.OvsBridgeName)
 	if err != nil {
-		log.Errorf("Error creating the default bridge. It probably already exists")
+		log.Errorf("Error creating the bridge %s. It probably already exists", bridgeName)
 		log.Errorf("Error: %v", err)
 	}
 
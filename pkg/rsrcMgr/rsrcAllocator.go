@@ -16,6 +16,9 @@ func rsrcMgrLoop() {
 
 		case useMsg := <-rsrcMgr.userChan:
 			rsrcUseMsg(useMsg)
+
+		case queryMsg := <-rsrcMgr.queryChan:
+			queryMsg.RespChan <- rsrcSnapshot(queryMsg.Type, queryMsg.Provider)
 		}
 	}
 }
@@ -85,6 +88,13 @@ func rsrcProviderAdd(rsrcPrvd ResourceProvide) error {
 
 	// Check if the provider already exist
 	if rsrc.Providers[rcrcProvider] != nil {
+		provider := rsrc.Providers[rcrcProvider]
+
+		// Grow the provider if it has fewer resources than requested
+		if (provider.UnitType == rsrcPrvd.UnitType) && (provider.NumRsrc < rsrcPrvd.NumRsrc) {
+			return rsrcProviderGrow(provider, rsrcPrvd.NumRsrc)
+		}
+
 		// FIXME: handle this gracefully
 		return nil
 	}
@@ -117,6 +127,34 @@ func rsrcProviderAdd(rsrcPrvd ResourceProvide) error {
 	return nil
 }
 
+// Grow a provider to have more resources
+func rsrcProviderGrow(provider *RsrcProvider, numRsrc float64) error {
+	log.Infof("Growing resource provider %s/%s from %v to %v", provider.Type,
+		provider.Provider, provider.NumRsrc, numRsrc)
+
+	provider.FreeRsrc += numRsrc - provider.NumRsrc
+	provider.NumRsrc = numRsrc
+
+	// For descrete units, rebuild a bigger bitmap
+	if provider.UnitType == "descrete" {
+		provider.rsrcBitset = bitset.New(uint(provider.NumRsrc))
+		for _, user := range provider.RsrcUsers {
+			for _, rsrcIndex := range user.RsrcIndexes {
+				provider.rsrcBitset.Set(uint(rsrcIndex))
+			}
+		}
+	}
+
+	// Store the resource change onto confStore
+	err := cdbSaveProvider(provider)
+	if err != nil {
+		log.Errorf("Error saving provider to conf store")
+		return err
+	}
+
+	return nil
+}
+
 // Restore a resource provider state
 func rsrcProviderRestore(provider *RsrcProvider) error {
 	rsrcType := provider.Type
@@ -196,9 +234,25 @@ func rsrcProviderRemove(rsrcPrvd ResourceProvide) error {
 func rsrcUseMsg(useMsg ResourceUserMsg) {
 	var rsrcRespList []ResourceUseResp
 
-	// First Pass. make sure all operations can succed
+	// Let the caller select resources based on current state
+	if useMsg.RsrcOp == "selectAlloc" {
+		rsrcList, err := useMsg.SelectFunc(rsrcSnapshot("", ""))
+		if err != nil {
+			rsrcUseResp(useMsg, rsrcRespList, err)
+			return
+		}
+
+		useMsg.RsrcOp = "alloc"
+		useMsg.ResourceList = rsrcList
+	}
+
+	// First Pass. make sure all operations can succed. Keep track of what
+	// earlier entries consume so that a group can not overcommit a provider
+	pendingRsrc := make(map[string]float64)
 	for _, rsrcUse := range useMsg.ResourceList {
-		err := rsrcUseCheck(rsrcUse, useMsg.RsrcOp)
+		pendingKey := rsrcUse.Type + "/" + rsrcUse.Provider
+		err := rsrcUseCheck(rsrcUse, useMsg.RsrcOp, pendingRsrc[pendingKey])
+		pendingRsrc[pendingKey] += rsrcUse.NumRsrc
 		if err != nil {
 			log.Errorf("Error: %v. Resource op %+v can not be performed", err, rsrcUse)
 
@@ -249,8 +303,9 @@ func rsrcUseResp(useMsg ResourceUserMsg, rsrcList []ResourceUseResp, err error)
 	useMsg.RespChan <- resp
 }
 
-// Check if we can perform resource use operation
-func rsrcUseCheck(rsrcUse ResourceUse, rsrcOp string) error {
+// Check if we can perform resource use operation.
+// pendingRsrc is the amount earlier entries in the same request allocate from the provider
+func rsrcUseCheck(rsrcUse ResourceUse, rsrcOp string, pendingRsrc float64) error {
 	rsrcType := rsrcUse.Type
 	rcrcProvider := rsrcUse.Provider
 
@@ -268,13 +323,21 @@ func rsrcUseCheck(rsrcUse ResourceUse, rsrcOp string) error {
 		return errors.New("Resource Provider does not exist")
 	}
 
+	// Make sure we are not freeing resource that was never allocated. Its
+	// better to fail here than in second phase
+	if (rsrcOp == "free") && (provider.RsrcUsers[rsrcUse.UserKey] == nil) {
+		log.Errorf("Resource %s/%s was not allocated for %s", rsrcType,
+			rcrcProvider, rsrcUse.UserKey)
+		return errors.New("Resource not allocated for user")
+	}
+
 	// Nothing more to check if this is not an alloc message
 	if rsrcOp != "alloc" {
 		return nil
 	}
 
 	// Make sure there is enough resource
-	if provider.FreeRsrc < rsrcUse.NumRsrc {
+	if provider.FreeRsrc < pendingRsrc+rsrcUse.NumRsrc {
 		log.Errorf("Not enough resource available. Req: %+v, Avl: %+v", rsrcUse, provider)
 		return errors.New("Not enough resource available")
 	}
@@ -297,6 +360,7 @@ func rsrcAlloc(rsrcUse ResourceUse) (*ResourceUseResp, error) {
 	resp := ResourceUseResp{
 		Type:     rsrcType,
 		Provider: rcrcProvider,
+		UserKey:  rsrcUse.UserKey,
 		NumRsrc:  rsrcUse.NumRsrc,
 	}
 
@@ -380,6 +444,7 @@ func rsrcFree(rsrcUse ResourceUse) (*ResourceUseResp, error) {
 	resp := ResourceUseResp{
 		Type:     rsrcType,
 		Provider: rcrcProvider,
+		UserKey:  rsrcUse.UserKey,
 		NumRsrc:  rsrcUse.NumRsrc,
 	}
 
@@ -427,3 +492,42 @@ func rsrcFree(rsrcUse ResourceUse) (*ResourceUseResp, error) {
 
 	return &resp, nil
 }
+
+// Make a copy of providers so that callers can read them safely.
+// Empty type or provider id matches all of them
+func rsrcSnapshot(rsrcType string, providerId string) Snapshot {
+	snapshot := make(Snapshot)
+
+	for typeName, rsrc := range rsrcMgr.rsrcDb {
+		if (rsrcType != "") && (typeName != rsrcType) {
+			continue
+		}
+
+		snapshot[typeName] = make(map[string]*RsrcProvider)
+		for prvdId, provider := range rsrc.Providers {
+			if (providerId != "") && (prvdId != providerId) {
+				continue
+			}
+
+			snapshot[typeName][prvdId] = copyProvider(provider)
+		}
+	}
+
+	return snapshot
+}
+
+// Copy a provider and its users
+func copyProvider(provider *RsrcProvider) *RsrcProvider {
+	providerCopy := *provider
+	providerCopy.rsrcBitset = nil
+
+	// Copy the users
+	providerCopy.RsrcUsers = make(map[string]*RsrcUser)
+	for userKey, user := range provider.RsrcUsers {
+		userCopy := *user
+		userCopy.RsrcIndexes = append([]uint64{}, user.RsrcIndexes...)
+		providerCopy.RsrcUsers[userKey] = &userCopy
+	}
+
+	return &providerCopy
+}
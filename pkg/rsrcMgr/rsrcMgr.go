@@ -40,6 +40,7 @@ type Resource struct {
 type ResourceUseResp struct {
 	Type        string   // Type of resource
 	Provider    string   // Resource provider where resource is from
+	UserKey     string   // User the resource was allocated to
 	NumRsrc     float64  // Number of resources allocated
 	RsrcIndexes []uint64 // for descrete resources, index allocated
 }
@@ -58,13 +59,28 @@ type ResourceUse struct {
 	NumRsrc  float64 // Number of resources needed
 }
 
+// Snapshot of resource providers by resource type and provider id
+type Snapshot map[string]map[string]*RsrcProvider
+
+// Function that selects resources to allocate from a snapshot of providers.
+// Its called from resource manager loop, so it must not call rsrcMgr APIs
+type SelectFunc func(snapshot Snapshot) ([]ResourceUse, error)
+
 // Resource request messages
 type ResourceUserMsg struct {
-	RsrcOp       string                  // "alloc" or "free"
+	RsrcOp       string                  // "alloc", "free" or "selectAlloc"
 	ResourceList []ResourceUse           // List of resources to be requested
+	SelectFunc   SelectFunc              // Selects the resources for "selectAlloc"
 	RespChan     chan ResourceUseRespMsg // Channel for the response
 }
 
+// Read only query for current state of providers
+type ResourceQueryMsg struct {
+	Type     string        // Resource type to copy. All types if empty
+	Provider string        // Provider to copy. All providers of the type if empty
+	RespChan chan Snapshot // Channel for the response
+}
+
 type ResourceProvideResp struct {
 	Error error // nil on success or an error
 }
@@ -90,6 +106,7 @@ type RsrcMgr struct {
 	cdb          objdb.ObjdbApi          // conf store client
 	providerChan chan ResourceProvideMsg // Channel for provider msg
 	userChan     chan ResourceUserMsg    // Channel for user message
+	queryChan    chan ResourceQueryMsg   // Channel for query message
 }
 
 // Resource manager
@@ -104,6 +121,7 @@ func Init(cdb objdb.ObjdbApi) {
 	rsrcMgr.rsrcDb = make(map[string]*Resource)
 	rsrcMgr.providerChan = make(chan ResourceProvideMsg, 200)
 	rsrcMgr.userChan = make(chan ResourceUserMsg, 200)
+	rsrcMgr.queryChan = make(chan ResourceQueryMsg, 200)
 
 	// Start the resource mgr loop
 	go rsrcMgrLoop()
@@ -166,24 +184,35 @@ func AddResourceProvider(rsrcList []ResourceProvide) error {
 	return resp.Error
 }
 
-// Find a resource provider
-func FindResourceProvider(rsrcType string, rsrcProvider string) *RsrcProvider {
-	// If the resource type is unknown, return nil
-	if rsrcMgr.rsrcDb[rsrcType] == nil {
-		return nil
-	}
+// Return a copy of current state of all providers
+func GetSnapshot() Snapshot {
+	return querySnapshot("", "")
+}
 
-	// Return the provider
-	return rsrcMgr.rsrcDb[rsrcType].Providers[rsrcProvider]
+// Return a copy of a provider's state. Returns nil if it doesnt exist
+func FindResourceProvider(rsrcType string, rsrcProvider string) *RsrcProvider {
+	return querySnapshot(rsrcType, rsrcProvider)[rsrcType][rsrcProvider]
 }
 
+// Return a copy of all providers of a resource type. Returns nil if the type doesnt exist
 func ListProviders(rsrcType string) map[string]*RsrcProvider {
-	// Check if resource type exists
-	if rsrcMgr.rsrcDb[rsrcType] == nil {
-		return nil
+	return querySnapshot(rsrcType, "")[rsrcType]
+}
+
+// Copy the state of matching providers
+func querySnapshot(rsrcType string, rsrcProvider string) Snapshot {
+	// Create response channel
+	respChan := make(chan Snapshot, 1)
+
+	// Send the message
+	rsrcMgr.queryChan <- ResourceQueryMsg{
+		Type:     rsrcType,
+		Provider: rsrcProvider,
+		RespChan: respChan,
 	}
 
-	return rsrcMgr.rsrcDb[rsrcType].Providers
+	// Block on the response
+	return <-respChan
 }
 
 // Remove a resource provider
@@ -230,7 +259,29 @@ func AllocResources(rsrcList []ResourceUse) ([]ResourceUseResp, error) {
 	return resp.ResourceList, resp.Error
 }
 
-// Allocate one or more resources
+// Select resources using the select function and allocate them in one step.
+// Nothing can change the providers between selection and allocation
+func SelectAndAlloc(selectFunc SelectFunc) ([]ResourceUseResp, error) {
+	// Create response channel
+	respChan := make(chan ResourceUseRespMsg, 1)
+
+	// Build the message to send
+	msg := ResourceUserMsg{
+		RsrcOp:     "selectAlloc",
+		SelectFunc: selectFunc,
+		RespChan:   respChan,
+	}
+
+	// Send the message
+	rsrcMgr.userChan <- msg
+
+	// Block on the response
+	resp := <-respChan
+
+	return resp.ResourceList, resp.Error
+}
+
+// Free one or more resources
 func FreeResources(rsrcList []ResourceUse) error {
 	// Create response channel
 	respChan := make(chan ResourceUseRespMsg, 1)
@@ -1,6 +1,9 @@
 package rsrcMgr
 
 import (
+	"errors"
+	"fmt"
+	"sync"
 	"testing"
 
 	log "github.com/Sirupsen/logrus"
@@ -151,3 +154,248 @@ func TestAllocResourceFail(t *testing.T) {
 
 	log.Infof("Provider State: %#v", rsrcMgr.rsrcDb["vlan"].Providers["global"])
 }
+
+func TestFreeResource(t *testing.T) {
+	// resource list
+	rsrcList := []ResourceUse{
+		{
+			Type:     "cpu",
+			Provider: "host1",
+			UserKey:  "alta1234",
+			NumRsrc:  2,
+		},
+		{
+			Type:     "memory",
+			Provider: "host1",
+			UserKey:  "alta1234",
+			NumRsrc:  1 * 1024,
+		},
+	}
+
+	// Free the resource
+	err := FreeResources(rsrcList)
+	if err != nil {
+		t.Errorf("Error freeing cpu/mem resource. Err: %v", err)
+	}
+
+	if rsrcMgr.rsrcDb["cpu"].Providers["host1"].FreeRsrc != 4 {
+		t.Errorf("cpu resource was not freed: %#v", rsrcMgr.rsrcDb["cpu"].Providers["host1"])
+	}
+
+	// Freeing it again should fail gracefully
+	err = FreeResources(rsrcList)
+	if err == nil {
+		t.Errorf("No Error freeing an unallocated resource")
+	}
+
+	// Free the vlan resources
+	rsrcList = []ResourceUse{
+		{
+			Type:     "vlan",
+			Provider: "global",
+			UserKey:  "net1234",
+			NumRsrc:  5,
+		},
+	}
+	err = FreeResources(rsrcList)
+	if err != nil {
+		t.Errorf("Error freeing vlan resource. Err: %v", err)
+	}
+
+	log.Infof("Provider State: %#v", rsrcMgr.rsrcDb["vlan"].Providers["global"])
+}
+
+// Select the provider with most free slots and allocate one slot from it
+func selectLeastUsed(userKey string) SelectFunc {
+	return func(snapshot Snapshot) ([]ResourceUse, error) {
+		var best *RsrcProvider
+		for _, provider := range snapshot["slot"] {
+			if (provider.FreeRsrc >= 1) && ((best == nil) || (provider.FreeRsrc > best.FreeRsrc)) {
+				best = provider
+			}
+		}
+		if best == nil {
+			return nil, errors.New("No provider with free slot")
+		}
+
+		return []ResourceUse{{Type: "slot", Provider: best.Provider, UserKey: userKey, NumRsrc: 1}}, nil
+	}
+}
+
+// Concurrent select and allocate must never over allocate
+func TestSelectAndAllocConcurrent(t *testing.T) {
+	if rsrcMgr == nil {
+		Init(nil)
+	}
+
+	// Add 4 hosts with 25 slots each
+	var provider []ResourceProvide
+	for i := 0; i < 4; i++ {
+		provider = append(provider, ResourceProvide{
+			Type:     "slot",
+			Provider: fmt.Sprintf("host%d", i),
+			UnitType: "fluid",
+			NumRsrc:  25,
+		})
+	}
+	err := AddResourceProvider(provider)
+	if err != nil {
+		t.Fatalf("Error adding provider %+v. Err: %v", provider, err)
+	}
+
+	// Try to allocate 300 slots concurrently while reading snapshots
+	var wg sync.WaitGroup
+	var mutex sync.Mutex
+	numAlloc := 0
+	for i := 0; i < 300; i++ {
+		wg.Add(1)
+		go func(idx int) {
+			defer wg.Done()
+
+			_, err := SelectAndAlloc(selectLeastUsed(fmt.Sprintf("alta%d", idx)))
+			if err == nil {
+				mutex.Lock()
+				numAlloc++
+				mutex.Unlock()
+			}
+
+			// Read only callers get a copy
+			for _, provider := range ListProviders("slot") {
+				provider.FreeRsrc = -1
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if numAlloc != 100 {
+		t.Errorf("Expected 100 allocations, got %d", numAlloc)
+	}
+
+	// Every host should be fully used, never over used
+	for providerId, provider := range ListProviders("slot") {
+		if (provider.FreeRsrc != 0) || (provider.UsedRsrc != 25) || (len(provider.RsrcUsers) != 25) {
+			t.Errorf("Unexpected state for provider %s: %+v", providerId, provider)
+		}
+	}
+
+	// Selection errors are returned to the caller
+	_, err = SelectAndAlloc(selectLeastUsed("altaX"))
+	if err == nil {
+		t.Errorf("Allocation succeeded with no free slot")
+	}
+}
+
+// Test a group allocation that overcommits a provider allocates nothing
+func TestAllocGroupOvercommit(t *testing.T) {
+	if rsrcMgr == nil {
+		Init(nil)
+	}
+
+	provider := []ResourceProvide{{Type: "gpu", Provider: "host1", UnitType: "fluid", NumRsrc: 4}}
+	err := AddResourceProvider(provider)
+	if err != nil {
+		t.Fatalf("Error adding provider %+v. Err: %v", provider, err)
+	}
+
+	// Each member fits by itself, but not all of them together
+	rsrcList := []ResourceUse{
+		{Type: "gpu", Provider: "host1", UserKey: "member1", NumRsrc: 2},
+		{Type: "gpu", Provider: "host1", UserKey: "member2", NumRsrc: 2},
+		{Type: "gpu", Provider: "host1", UserKey: "member3", NumRsrc: 2},
+	}
+	_, err = AllocResources(rsrcList)
+	if err == nil {
+		t.Errorf("Group allocation overcommitted the provider")
+	}
+
+	gpuProvider := FindResourceProvider("gpu", "host1")
+	if (gpuProvider.FreeRsrc != 4) || (len(gpuProvider.RsrcUsers) != 0) {
+		t.Errorf("Failed group allocation left resources allocated: %+v", gpuProvider)
+	}
+
+	// Members that fit are all allocated
+	respList, err := AllocResources(rsrcList[:2])
+	if err != nil {
+		t.Fatalf("Error allocating group. Err: %v", err)
+	}
+	if (len(respList) != 2) || (respList[0].UserKey != "member1") || (respList[1].UserKey != "member2") {
+		t.Errorf("Unexpected group allocation response: %+v", respList)
+	}
+}
+
+func TestGrowProvider(t *testing.T) {
+	if rsrcMgr == nil {
+		Init(nil)
+	}
+
+	provider := []ResourceProvide{{Type: "macaddr", Provider: "global", UnitType: "descrete", NumRsrc: 2}}
+	err := AddResourceProvider(provider)
+	if err != nil {
+		t.Fatalf("Error adding provider %+v. Err: %v", provider, err)
+	}
+
+	// Use up the provider
+	rsrcList := []ResourceUse{
+		{Type: "macaddr", Provider: "global", UserKey: "ep1", NumRsrc: 1},
+		{Type: "macaddr", Provider: "global", UserKey: "ep2", NumRsrc: 1},
+	}
+	_, err = AllocResources(rsrcList)
+	if err != nil {
+		t.Fatalf("Error allocating resources. Err: %v", err)
+	}
+	newUse := []ResourceUse{{Type: "macaddr", Provider: "global", UserKey: "ep3", NumRsrc: 1}}
+	_, err = AllocResources(newUse)
+	if err == nil {
+		t.Errorf("Allocated from a full provider")
+	}
+
+	// Adding the provider again with more resources grows it
+	provider[0].NumRsrc = 4
+	err = AddResourceProvider(provider)
+	if err != nil {
+		t.Fatalf("Error growing provider %+v. Err: %v", provider, err)
+	}
+
+	respList, err := AllocResources(newUse)
+	if err != nil {
+		t.Fatalf("Error allocating from grown provider. Err: %v", err)
+	}
+	if respList[0].RsrcIndexes[0] != 2 {
+		t.Errorf("Allocated index %d from grown provider", respList[0].RsrcIndexes[0])
+	}
+
+	macProvider := FindResourceProvider("macaddr", "global")
+	if (macProvider.NumRsrc != 4) || (macProvider.FreeRsrc != 1) || (macProvider.UsedRsrc != 3) {
+		t.Errorf("Unexpected grown provider state: %+v", macProvider)
+	}
+}
+
+// Test provider queries copy only what was asked for
+func TestQueryProvider(t *testing.T) {
+	if rsrcMgr == nil {
+		Init(nil)
+	}
+
+	provider := []ResourceProvide{
+		{Type: "port", Provider: "host1", UnitType: "descrete", NumRsrc: 10},
+		{Type: "port", Provider: "host2", UnitType: "descrete", NumRsrc: 10},
+	}
+	err := AddResourceProvider(provider)
+	if err != nil {
+		t.Fatalf("Error adding provider %+v. Err: %v", provider, err)
+	}
+
+	snapshot := querySnapshot("port", "host1")
+	if (len(snapshot) != 1) || (len(snapshot["port"]) != 1) || (snapshot["port"]["host1"] == nil) {
+		t.Errorf("Provider query returned unexpected state: %+v", snapshot)
+	}
+	if len(ListProviders("port")) != 2 {
+		t.Errorf("Expected 2 port providers. Got: %+v", ListProviders("port"))
+	}
+	if FindResourceProvider("port", "host3") != nil {
+		t.Errorf("Found provider that was never added")
+	}
+	if ListProviders("noSuchType") != nil {
+		t.Errorf("Found providers of unknown resource type")
+	}
+}
@@ -2,8 +2,11 @@ package altaCtrler
 
 import (
 	"errors"
+	"fmt"
+	"sync"
 	"time"
 
+	"github.com/contiv/symphony/zeus/common"
 	"github.com/contiv/symphony/zeus/netCtrler"
 	"github.com/contiv/symphony/zeus/nodeCtrler"
 	"github.com/contiv/symphony/zeus/scheduler"
@@ -11,6 +14,7 @@ import (
 
 	"github.com/contiv/symphony/pkg/altaspec"
 	"github.com/contiv/symphony/pkg/libfsm"
+	"github.com/contiv/symphony/pkg/rsrcMgr"
 
 	log "github.com/Sirupsen/logrus"
 )
@@ -21,27 +25,117 @@ type AltaModel struct {
 	CurrNode    string            // Node where this container is placed
 	ContainerId string            // ContainerId on current node
 	Fsm         *libfsm.Fsm       // FSM for the container
+
+	RestartCount int       // Number of restarts since last stable run
+	NextRestart  time.Time // When to attempt the next restart
+	LastRestart  time.Time // When the container was last restarted
+
+	NumRetries    int       // Number of failed attempts in current state
+	LastError     string    // Last error seen while processing an event
+	StateDeadline time.Time // When to retry if we are stuck in current state
+
+	Ready    bool // Readiness probe result reported by the node
+	ExitCode int  // Exit code when the container last exited
+
+	PendingReasons []common.NodeRejection   // Why the alta could not be scheduled
+	Events         []common.AltaEventRecord // Notable events, oldest first
+}
+
+// Max number of events remembered per alta
+const maxAltaEvents = 20
+
+// How long to wait for a victim to leave its node before giving up on it
+const preemptTimeout = time.Second * 30
+
+// Request to evict an alta for a higher priority alta
+type preemptReq struct {
+	preemptorId string        // Alta that needs the resources
+	priority    int           // Priority of the preempting alta
+	err         error         // Result of the request. Set before doneChan is closed
+	doneChan    chan struct{} // Closed once the request was processed
+}
+
+// Container status reported by the node
+type nodeStatus struct {
+	ready    bool // Readiness probe result
+	exited   bool // Container exited
+	exitCode int  // Exit code if the container exited
+}
+
+// How long a user request waits for the alta to process it
+const userEventTimeout = time.Second * 30
+
+// Event requested by the user. Actor validates it and replies with the result
+type userEventReq struct {
+	toNode    string     // Node to move to for migrate event. Any node if empty
+	replyChan chan error // Result of the event
+}
+
+// Copy of the alta state that other goroutines can read
+type altaSnapshot struct {
+	common.AltaState
+	Preemptible bool // Alta can be preempted in its current state
 }
 
+// Retry behavior for a state
+type stateRetry struct {
+	timeout    time.Duration // How long to wait in the state before retrying
+	retryEvent string        // Event to retry
+	giveUp     bool          // Move to error state after max retries
+}
+
+// Retry table for the states where alta can get stuck
+var altaStateRetry = map[string]stateRetry{
+	"created":      {time.Second * 15, "schedule", false},
+	"rescheduling": {time.Second * 15, "schedule", false},
+	"scheduled":    {time.Second * 30, "createNet", true},
+	"waitVol":      {time.Minute * 1, "createVol", true},
+	"waitImg":      {time.Minute * 2, "pullImg", true},
+	"creating":     {time.Second * 30, "imgReady", true},
+	"starting":     {time.Second * 30, "start", true},
+}
+
+// Default number of retries before giving up
+const defaultMaxRetries = 5
+
+const (
+	restartBackoffBase = time.Second * 15 // Initial delay before restarting
+	restartBackoffMax  = time.Minute * 5  // Max delay between restarts
+	restartResetTime   = time.Minute * 10 // Stable run time to reset restart count
+)
+
 // State of Alta container
 type AltaActor struct {
 	AltaId    string            // Unique Id for the container
 	Model     AltaModel         // State of the alta container
 	EventChan chan libfsm.Event // Event queue
 	ticker    *time.Ticker      // DEBUG: ticker to print state
+	exitChan  chan struct{}     // Closed when the runloop exits
+
+	stateMutex sync.RWMutex // Lock for the published state
+	state      altaSnapshot // State published after each event
 }
 
 // Create a new Alta container
 func NewAlta(altaSpec *altaspec.AltaSpec) (*AltaActor, error) {
+	alta := newAlta(altaSpec)
+
+	// Kick off the alta runloop
+	alta.start()
+
+	return alta, nil
+}
+
+// Build the alta actor without starting it
+func newAlta(altaSpec *altaspec.AltaSpec) *AltaActor {
 	alta := new(AltaActor)
 
 	// initialize
 	alta.AltaId = altaSpec.AltaId
 	alta.Model.Spec = *altaSpec
 
-	// Start alta FSM in its own go routine
-	// FSM for the node
-	alta.Model.Fsm = libfsm.NewFsm(&libfsm.FsmTable{
+	// FSM transitions common to all kinds of altas
+	fsmTable := libfsm.FsmTable{
 		// currentState,  event,      newState,   callback
 		{"created", "schedule", "scheduled", func(e libfsm.Event) error { return alta.scheduleAlta() }},
 		{"scheduled", "createNet", "waitVol", func(e libfsm.Event) error { return alta.createNetwork() }},
@@ -49,27 +143,85 @@ func NewAlta(altaSpec *altaspec.AltaSpec) (*AltaActor, error) {
 		{"waitImg", "pullImg", "creating", func(e libfsm.Event) error { return alta.pullImg() }},
 		{"creating", "imgReady", "starting", func(e libfsm.Event) error { return alta.createAltaCntr() }},
 		{"starting", "start", "running", func(e libfsm.Event) error { return alta.startAltaCntr() }},
-		{"running", "failure", "failed", func(e libfsm.Event) error { return alta.restartAltaCntr() }},
-		{"running", "stop", "stopped", func(e libfsm.Event) error { return alta.stopAltaCntr() }},
 		{"running", "nodeFailure", "rescheduling", func(e libfsm.Event) error { return alta.rescheduleAltaCntr() }},
-		{"failed", "failure", "failed", func(e libfsm.Event) error { return alta.restartAltaCntr() }},
-		{"failed", "restart", "running", func(e libfsm.Event) error { return alta.startAltaCntr() }},
-		{"stopped", "start", "running", func(e libfsm.Event) error { return alta.startAltaCntr() }},
 		{"rescheduling", "schedule", "scheduled", func(e libfsm.Event) error { return alta.scheduleAlta() }},
-	}, "created")
+		{"created", "delete", "deleted", func(e libfsm.Event) error { return alta.deleteAlta() }},
+		{"scheduled", "delete", "deleted", func(e libfsm.Event) error { return alta.deleteAlta() }},
+		{"waitVol", "delete", "deleted", func(e libfsm.Event) error { return alta.deleteAlta() }},
+		{"waitImg", "delete", "deleted", func(e libfsm.Event) error { return alta.deleteAlta() }},
+		{"creating", "delete", "deleted", func(e libfsm.Event) error { return alta.deleteAlta() }},
+		{"starting", "delete", "deleted", func(e libfsm.Event) error { return alta.deleteAlta() }},
+		{"running", "delete", "deleted", func(e libfsm.Event) error { return alta.deleteAlta() }},
+		{"failed", "delete", "deleted", func(e libfsm.Event) error { return alta.deleteAlta() }},
+		{"error", "delete", "deleted", func(e libfsm.Event) error { return alta.deleteAlta() }},
+		{"rescheduling", "delete", "deleted", func(e libfsm.Event) error { return alta.deleteAlta() }},
+		{"scheduled", "retryExhausted", "error", func(e libfsm.Event) error { return alta.altaCntrError() }},
+		{"waitVol", "retryExhausted", "error", func(e libfsm.Event) error { return alta.altaCntrError() }},
+		{"waitImg", "retryExhausted", "error", func(e libfsm.Event) error { return alta.altaCntrError() }},
+		{"creating", "retryExhausted", "error", func(e libfsm.Event) error { return alta.altaCntrError() }},
+		{"starting", "retryExhausted", "error", func(e libfsm.Event) error { return alta.altaCntrError() }},
+		{"scheduled", "preempt", "created", func(e libfsm.Event) error { return alta.preemptAlta(e) }},
+		{"waitVol", "preempt", "created", func(e libfsm.Event) error { return alta.preemptAlta(e) }},
+		{"waitImg", "preempt", "created", func(e libfsm.Event) error { return alta.preemptAlta(e) }},
+		{"creating", "preempt", "created", func(e libfsm.Event) error { return alta.preemptAlta(e) }},
+		{"starting", "preempt", "created", func(e libfsm.Event) error { return alta.preemptAlta(e) }},
+		{"running", "preempt", "created", func(e libfsm.Event) error { return alta.preemptAlta(e) }},
+	}
+
+	if altaSpec.Kind == "job" {
+		// Jobs run to completion and are never restarted
+		fsmTable = append(fsmTable, libfsm.FsmTable{
+			{"running", "complete", "succeeded", func(e libfsm.Event) error { return alta.jobAltaDone() }},
+			{"running", "failure", "failed", func(e libfsm.Event) error { return alta.jobAltaDone() }},
+			{"succeeded", "delete", "deleted", func(e libfsm.Event) error { return alta.deleteAlta() }},
+		}...)
+	} else {
+		// Long running services are restarted based on restart policy
+		fsmTable = append(fsmTable, libfsm.FsmTable{
+			{"running", "failure", "failed", func(e libfsm.Event) error { return alta.altaCntrFailed() }},
+			{"running", "complete", "stopped", func(e libfsm.Event) error { return alta.altaCntrExited() }},
+			{"running", "userStop", "stopped", func(e libfsm.Event) error { return alta.stopAltaCntr() }},
+			{"running", "userRestart", "running", func(e libfsm.Event) error { return alta.userRestartAltaCntr() }},
+			{"running", "migrate", "rescheduling", func(e libfsm.Event) error { return alta.migrateAlta(e) }},
+			{"failed", "failure", "failed", func(e libfsm.Event) error { return nil }},
+			{"failed", "restartTimer", "running", func(e libfsm.Event) error { return alta.restartAltaCntr() }},
+			{"failed", "giveUp", "gaveUp", func(e libfsm.Event) error { return alta.stopFailedAltaCntr() }},
+			{"failed", "userRestart", "running", func(e libfsm.Event) error { return alta.userStartAltaCntr() }},
+			{"failed", "userStop", "stopped", func(e libfsm.Event) error { return alta.stopFailedAltaCntr() }},
+			{"failed", "preempt", "created", func(e libfsm.Event) error { return alta.preemptAlta(e) }},
+			{"gaveUp", "failure", "gaveUp", func(e libfsm.Event) error { return nil }},
+			{"gaveUp", "userRestart", "running", func(e libfsm.Event) error { return alta.userStartAltaCntr() }},
+			{"gaveUp", "userStop", "stopped", func(e libfsm.Event) error { return nil }},
+			{"stopped", "userStart", "running", func(e libfsm.Event) error { return alta.userStartAltaCntr() }},
+			{"stopped", "userRestart", "running", func(e libfsm.Event) error { return alta.userStartAltaCntr() }},
+			{"stopped", "delete", "deleted", func(e libfsm.Event) error { return alta.deleteAlta() }},
+			{"gaveUp", "delete", "deleted", func(e libfsm.Event) error { return alta.deleteAlta() }},
+		}...)
+	}
+
+	// Create the FSM
+	alta.Model.Fsm = libfsm.NewFsm(&fsmTable, "created")
 
 	// create the channel
 	alta.EventChan = make(chan libfsm.Event, 200)
+	alta.exitChan = make(chan struct{})
 
-	// timer to periodically retry in failed states
-	alta.ticker = time.NewTicker(time.Second * 15)
+	// Set the deadline for initial state
+	alta.setStateDeadline()
 
-	// Kick off the alta runloop
-	go alta.runLoop()
+	return alta
+}
 
-	log.Infof("Created Alta: %#v", alta)
+// Start the alta runloop
+func (self *AltaActor) start() {
+	self.publishState()
 
-	return alta, nil
+	// timer to periodically retry in failed states
+	self.ticker = time.NewTicker(time.Second * 15)
+
+	go self.runLoop()
+
+	log.Infof("Created Alta: %#v", self)
 }
 
 // Main run loop for the alta container.
@@ -78,19 +230,76 @@ func (self *AltaActor) runLoop() {
 	for {
 		select {
 		case event := <-self.EventChan:
-			self.Model.Fsm.FsmEvent(event)
+			var err error
+			prevState := self.Model.Fsm.FsmState
+			if status, ok := event.EventData.(*nodeStatus); ok {
+				// Status reported by the node does not change the state
+				self.updateNodeStatus(status)
+			} else if req, ok := event.EventData.(*userEventReq); ok {
+				err = self.userEvent(event, req)
+			} else {
+				err = self.Model.Fsm.FsmEvent(event)
+			}
+			if err != nil && err != libfsm.ErrInvalidEvent {
+				self.eventFailed(event.EventName, err)
+			} else if self.Model.Fsm.FsmState != prevState {
+				// Moved to a new state, reset retry counters
+				self.Model.NumRetries = 0
+				self.setStateDeadline()
+			}
+
+			// Let other goroutines see the new state
+			self.publishState()
+
+			// Let the preempting alta know we are done
+			if req, ok := event.EventData.(*preemptReq); ok {
+				req.err = err
+				close(req.doneChan)
+			}
+
+			// If the alta was deleted, remove it from the DB and stop the actor
+			if self.Model.Fsm.FsmState == "deleted" {
+				self.ticker.Stop()
+				altaCtrl.removeAlta(self)
+				close(self.exitChan)
+				return
+			}
 
 			// Save state after each transition
 			self.saveModel()
 		case <-self.ticker.C:
-			// FIXME: Use this timer to perform retries when things fail
 			log.Debugf("Alta: %s, FSM state: %s, state: %#v", self.Model.Spec.AltaName,
 				self.Model.Fsm.FsmState, self)
 
-			// If we are stuck in created state, retry it periodically
-			if self.Model.Fsm.FsmState == "created" {
-				self.AltaEvent("schedule")
+			// If we are stuck in a state past its deadline, retry it
+			retry, ok := altaStateRetry[self.Model.Fsm.FsmState]
+			if ok {
+				if self.Model.StateDeadline.IsZero() {
+					self.setStateDeadline()
+				} else if time.Now().After(self.Model.StateDeadline) {
+					log.Infof("Alta %s stuck in state %s, retrying %s", self.AltaId,
+						self.Model.Fsm.FsmState, retry.retryEvent)
+					self.setStateDeadline()
+					self.AltaEvent(retry.retryEvent)
+				}
+			}
+
+			// Restart failed container when backoff timer expires
+			if (self.Model.Fsm.FsmState == "failed") && !self.Model.NextRestart.IsZero() &&
+				time.Now().After(self.Model.NextRestart) {
+				self.Model.NextRestart = time.Time{}
+				self.AltaEvent("restartTimer")
 			}
+
+			// Reset restart count once the container has been stable for a while
+			if (self.Model.Fsm.FsmState == "running") && (self.Model.RestartCount != 0) &&
+				(time.Since(self.Model.LastRestart) > restartResetTime) {
+				log.Infof("Alta %s is stable, resetting restart count", self.AltaId)
+				self.Model.RestartCount = 0
+				self.saveModel()
+			}
+
+			self.publishState()
 		}
 	}
 }
@@ -100,17 +309,125 @@ func (self *AltaActor) AltaEvent(eventName string) {
 	self.EventChan <- libfsm.Event{eventName, nil}
 }
 
+// Process an event requested by the user and reply with the result
+func (self *AltaActor) userEvent(event libfsm.Event, req *userEventReq) error {
+	// Make sure the event is allowed in current state
+	if !self.Model.Fsm.IsValidEvent(event.EventName) {
+		log.Errorf("Event %s not allowed for alta %s in state %s", event.EventName, self.AltaId,
+			self.Model.Fsm.FsmState)
+		req.replyChan <- common.ErrInvalidEvent
+		return nil
+	}
+
+	err := self.Model.Fsm.FsmEvent(event)
+	req.replyChan <- err
+
+	return err
+}
+
+// Update container status reported by the node
+func (self *AltaActor) updateNodeStatus(status *nodeStatus) {
+	if status.exited {
+		self.Model.ExitCode = status.exitCode
+	} else {
+		self.Model.Ready = status.ready
+	}
+}
+
+// Set the retry deadline for current state
+func (self *AltaActor) setStateDeadline() {
+	retry, ok := altaStateRetry[self.Model.Fsm.FsmState]
+	if !ok {
+		self.Model.StateDeadline = time.Time{}
+		return
+	}
+
+	self.Model.StateDeadline = time.Now().Add(retry.timeout)
+}
+
+// Remember a notable event so that user can see it in alta state
+func (self *AltaActor) recordEvent(reason, message string) {
+	self.Model.Events = append(self.Model.Events, common.AltaEventRecord{
+		Time:    time.Now(),
+		Reason:  reason,
+		Message: message,
+	})
+
+	// Keep only the latest events
+	if len(self.Model.Events) > maxAltaEvents {
+		self.Model.Events = self.Model.Events[len(self.Model.Events)-maxAltaEvents:]
+	}
+}
+
+// Record an event failure and give up if we retried too many times
+func (self *AltaActor) eventFailed(eventName string, err error) {
+	self.Model.LastError = err.Error()
+
+	// Only count failures of the event we retry in this state
+	retry, ok := altaStateRetry[self.Model.Fsm.FsmState]
+	if !ok || (retry.retryEvent != eventName) {
+		return
+	}
+
+	self.Model.NumRetries++
+	self.setStateDeadline()
+
+	// See if we need to give up
+	maxRetries := self.Model.Spec.SchedPolicy.MaxRetries
+	if maxRetries == 0 {
+		maxRetries = defaultMaxRetries
+	}
+	if retry.giveUp && (self.Model.NumRetries >= maxRetries) {
+		log.Errorf("Alta %s failed %d times in state %s. Giving up. Err: %v", self.AltaId,
+			self.Model.NumRetries, self.Model.Fsm.FsmState, err)
+		self.AltaEvent("retryExhausted")
+	}
+}
+
 // ****************** FSM event handlers ***************
 // Schedule the container to one of the nodes
 func (self *AltaActor) scheduleAlta() error {
 	// Ask the scheduler to assign a node
-	sched := scheduler.Scheduler(self.Model.Spec.SchedPolicy.SchedulerName)
-	nodeAddr, err := sched.ScheduleAlta(&self.Model.Spec)
+	sched, err := scheduler.Scheduler(self.Model.Spec.SchedPolicy.SchedulerName)
+	if err != nil {
+		log.Errorf("Error getting scheduler for alta %s. Err: %v", self.AltaId, err)
+		return err
+	}
+
+	var nodeAddr string
+	if self.Model.Spec.SchedPolicy.Gang != "" {
+		// Gang members are placed all together
+		nodeAddr, err = altaCtrl.scheduleGang(self)
+	} else {
+		nodeAddr, err = sched.ScheduleAlta(&self.Model.Spec)
+		if _, ok := err.(*scheduler.ScheduleError); ok && self.preemptLowerPriority() {
+			// Victims leave their nodes in the background. Retry timer
+			// schedules us again once they are gone
+			self.recordEvent("Preempting", "Waiting for lower priority altas to leave their node")
+		}
+	}
 	if err != nil {
 		log.Errorf("Failed to schedule node. Error: %v", err)
+
+		// Save the reasons so that user can see why its pending
+		if schedErr, ok := err.(*scheduler.ScheduleError); ok {
+			self.Model.PendingReasons = append([]common.NodeRejection{{Reason: schedErr.Message}},
+				schedErr.Rejections...)
+		} else {
+			self.Model.PendingReasons = []common.NodeRejection{{Reason: err.Error()}}
+		}
+
+		// Dont keep a migrating alta off its old node if it fits nowhere else
+		self.Model.Spec.SchedPolicy.AvoidNodes = nil
+		self.Model.Spec.SchedPolicy.TargetNode = ""
+
 		return err
 	}
 
+	self.Model.PendingReasons = nil
+	self.Model.Spec.SchedPolicy.AvoidNodes = nil
+	self.Model.Spec.SchedPolicy.TargetNode = ""
+
 	// Save the current node
 	self.Model.CurrNode = nodeAddr
 
@@ -120,13 +437,28 @@ func (self *AltaActor) scheduleAlta() error {
 	return nil
 }
 
+// Ask lower priority altas to leave their node so that this alta fits there.
+// Returns true if any alta was asked to leave
+func (self *AltaActor) preemptLowerPriority() bool {
+	preemption, err := scheduler.FindPreemption(&self.Model.Spec)
+	if err != nil {
+		log.Infof("Alta %s can not preempt other altas. Err: %v", self.AltaId, err)
+		return false
+	}
+
+	altaCtrl.preemptAltas(self, preemption.Victims)
+
+	return true
+}
+
 // Create networks on the host
 func (self *AltaActor) createNetwork() error {
 	// Loop thru each endpoint
 	for _, endpoint := range self.Model.Spec.Endpoints {
 		network, err := netCtrler.FindNetwork(endpoint.NetworkName)
 		if err != nil {
-			log.Errorf("Network %s not found while creating Alta: %+v", self.Model.Spec)
+			log.Errorf("Network %s not found while creating Alta: %+v", endpoint.NetworkName,
+				self.Model.Spec)
 			return errors.New("network not found")
 		}
 
@@ -195,10 +527,15 @@ func (self *AltaActor) pullImg() error {
 		return err
 	}
 
-	// Image is ready move forward
-	if resp.Success {
-		self.AltaEvent("imgReady")
+	// Check if image pull failed
+	if !resp.Success {
+		log.Errorf("Failed to pull image %s on host %s", self.Model.Spec.Image, self.Model.CurrNode)
+		return errors.New("Image pull failed")
 	}
+
+	// Image is ready move forward
+	self.AltaEvent("imgReady")
+
 	return nil
 }
 
@@ -259,16 +596,139 @@ func (self *AltaActor) stopAltaCntr() error {
 
 	// Check return code
 	if !resp.Success {
-		return errors.New("Alta failed to start")
+		return errors.New("Alta failed to stop")
+	}
+
+	return nil
+}
+
+// Stop a failed container so that we dont keep restarting it
+func (self *AltaActor) stopFailedAltaCntr() error {
+	// Container might have already exited. Ignore errors
+	err := self.stopAltaCntr()
+	if err != nil {
+		log.Warnf("Error stopping failed container %s. Err: %v", self.AltaId, err)
 	}
 
 	return nil
 }
 
+// Restart a running container on user request
+func (self *AltaActor) userRestartAltaCntr() error {
+	log.Infof("User restart of container %s on host %s", self.AltaId, self.Model.CurrNode)
+
+	// User restart resets the restart count
+	self.Model.RestartCount = 0
+	self.Model.NextRestart = time.Time{}
+
+	// First stop the alta
+	err := self.stopAltaCntr()
+	if err != nil {
+		log.Errorf("Error stopping container. Err: %v", err)
+		return err
+	}
+
+	// Start the container
+	err = self.startAltaCntr()
+	if err != nil {
+		log.Errorf("Error starting container. Err: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+// Alta could not be created after all retries
+func (self *AltaActor) altaCntrError() error {
+	log.Errorf("Alta %s moved to error state. Last error: %s", self.AltaId, self.Model.LastError)
+
+	return nil
+}
+
+// Service container exited successfully and does not need a restart
+func (self *AltaActor) altaCntrExited() error {
+	log.Infof("Container %s exited with code %d on host %s", self.AltaId, self.Model.ExitCode,
+		self.Model.CurrNode)
+
+	return nil
+}
+
+// Job container ran to completion. Release its resources but leave the
+// container around so that its logs can be read
+func (self *AltaActor) jobAltaDone() error {
+	log.Infof("Job alta %s finished with exit code %d on host %s", self.AltaId,
+		self.Model.ExitCode, self.Model.CurrNode)
+
+	self.freeAltaResources()
+
+	return nil
+}
+
+// Check if this is a job alta that finished
+func (self *AltaActor) isJobDone() bool {
+	return jobDone(&self.Model.Spec, self.Model.Fsm.FsmState)
+}
+
+// Check if an alta in the state is a job that finished
+func jobDone(spec *altaspec.AltaSpec, fsmState string) bool {
+	if spec.Kind != "job" {
+		return false
+	}
+
+	return (fsmState == "succeeded") || (fsmState == "failed")
+}
+
+// Container failed, decide if and when to restart it
+func (self *AltaActor) altaCntrFailed() error {
+	log.Infof("Container %s failed on host %s", self.AltaId, self.Model.CurrNode)
+
+	// Schedule a restart based on restart policy
+	self.scheduleRestart()
+
+	return nil
+}
+
+// Set the backoff timer for next restart or give up
+func (self *AltaActor) scheduleRestart() {
+	policy := self.Model.Spec.SchedPolicy
+
+	// See if we should restart at all.
+	// Note: containers exiting with zero code never get here for onFailure policy
+	if policy.RestartPolicy == "never" {
+		log.Infof("Restart policy is never. Not restarting alta %s", self.AltaId)
+		self.AltaEvent("giveUp")
+		return
+	}
+
+	// Check if we have exhausted the restarts
+	if (policy.NumRestart != 0) && (self.Model.RestartCount >= policy.NumRestart) {
+		log.Errorf("Alta %s restarted %d times. Giving up", self.AltaId, self.Model.RestartCount)
+		self.AltaEvent("giveUp")
+		return
+	}
+
+	// Exponential backoff between restarts
+	backoff := restartBackoffMax
+	if self.Model.RestartCount < 5 {
+		backoff = restartBackoffBase * (1 << uint(self.Model.RestartCount))
+		if backoff > restartBackoffMax {
+			backoff = restartBackoffMax
+		}
+	}
+
+	self.Model.NextRestart = time.Now().Add(backoff)
+
+	log.Infof("Restarting alta %s in %v", self.AltaId, backoff)
+}
+
 // Restart a failed container
 func (self *AltaActor) restartAltaCntr() error {
 	log.Infof("Restarting container %s on host %s", self.AltaId, self.Model.CurrNode)
 
+	// Keep track of restarts
+	self.Model.RestartCount++
+	self.Model.LastRestart = time.Now()
+
 	// First stop the alta
 	self.stopAltaCntr()
 
@@ -276,15 +736,23 @@ func (self *AltaActor) restartAltaCntr() error {
 	err := self.startAltaCntr()
 	if err != nil {
 		log.Errorf("Error starting container. Err: %v", err)
+
+		// Try again later
+		self.scheduleRestart()
 		return err
 	}
 
-	// Trigger the restart event since it was successful
-	self.AltaEvent("restart")
-
 	return nil
 }
 
+// Start a container on user request. Resets the restart count
+func (self *AltaActor) userStartAltaCntr() error {
+	self.Model.RestartCount = 0
+	self.Model.NextRestart = time.Time{}
+
+	return self.startAltaCntr()
+}
+
 // Schedule a container on different node
 func (self *AltaActor) rescheduleAltaCntr() error {
 	// walk all volumes and Unmount it
@@ -308,6 +776,195 @@ func (self *AltaActor) rescheduleAltaCntr() error {
 	return nil
 }
 
+// Evict the container from its node so that a higher priority alta can use it.
+// Alta goes back to created state and gets scheduled again
+func (self *AltaActor) preemptAlta(e libfsm.Event) error {
+	req, ok := e.EventData.(*preemptReq)
+	if !ok {
+		log.Errorf("Invalid preempt request %+v for alta %s", e.EventData, self.AltaId)
+		return errors.New("Invalid preempt request")
+	}
+
+	// Never give way to equal or lower priority altas
+	if req.priority <= self.Model.Spec.SchedPolicy.Priority {
+		log.Errorf("Alta %s(priority %d) can not preempt alta %s(priority %d)", req.preemptorId,
+			req.priority, self.AltaId, self.Model.Spec.SchedPolicy.Priority)
+		return errors.New("Only lower priority altas can be preempted")
+	}
+
+	log.Infof("Preempting alta %s on host %s for alta %s", self.AltaId, self.Model.CurrNode,
+		req.preemptorId)
+
+	self.recordEvent("Preempted", fmt.Sprintf("Evicted from node %s by alta %s with priority %d",
+		self.Model.CurrNode, req.preemptorId, req.priority))
+
+	// Remove the container and release its resources on the node
+	self.releaseNode()
+
+	// Clear the placement so that it gets scheduled again
+	self.Model.CurrNode = ""
+	self.Model.ContainerId = ""
+	self.Model.Ready = false
+	self.Model.NextRestart = time.Time{}
+
+	return nil
+}
+
+// Move the container off its node, e.g. to rebalance the cluster.
+// Scheduler avoids the current node when placing it again, and places it
+// only on the target node if one was requested
+func (self *AltaActor) migrateAlta(e libfsm.Event) error {
+	fromNode := self.Model.CurrNode
+	var toNode string
+	if req, ok := e.EventData.(*userEventReq); ok {
+		toNode = req.toNode
+	}
+
+	log.Infof("Migrating alta %s away from host %s to %q", self.AltaId, fromNode, toNode)
+
+	if toNode != "" {
+		self.recordEvent("Migrating", fmt.Sprintf("Moving from node %s to %s", fromNode, toNode))
+	} else {
+		self.recordEvent("Migrating", fmt.Sprintf("Moving away from node %s", fromNode))
+	}
+
+	// Remove the container and release its resources on the node
+	self.releaseNode()
+
+	// Clear the placement and schedule it on another node
+	self.Model.CurrNode = ""
+	self.Model.ContainerId = ""
+	self.Model.Ready = false
+	self.Model.Spec.SchedPolicy.AvoidNodes = []string{fromNode}
+	self.Model.Spec.SchedPolicy.TargetNode = toNode
+
+	self.AltaEvent("schedule")
+
+	return nil
+}
+
+// Delete the container and release all resources held by it
+func (self *AltaActor) deleteAlta() error {
+	log.Infof("Deleting alta %s on host %s", self.AltaId, self.Model.CurrNode)
+
+	// Remove the container and release its resources on the node
+	self.releaseNode()
+
+	// Release the placement another gang member picked for us
+	if self.Model.Spec.SchedPolicy.Gang != "" {
+		altaCtrl.cancelGangPlacement(self.AltaId)
+	}
+
+	// Release mac/ip addresses of all endpoints
+	for _, endpoint := range self.Model.Spec.Endpoints {
+		err := netCtrler.DeleteAltaEndpoint(self.AltaId, endpoint.NetworkName)
+		if err != nil {
+			log.Errorf("Error deleting endpoint for alta %s in network %s. Err: %v",
+				self.AltaId, endpoint.NetworkName, err)
+		}
+	}
+
+	// Remove the alta from conf store
+	storeKey := "alta/" + self.Model.Spec.AltaId
+	err := altaCtrl.cdb.DelObj(storeKey)
+	if err != nil {
+		log.Errorf("Error deleting object %s. Err: %v", storeKey, err)
+	}
+
+	return nil
+}
+
+// Remove the container from its node and release resources held on the node
+func (self *AltaActor) releaseNode() {
+	// Stop and remove the container if it was created on a node
+	if (self.Model.CurrNode != "") && (self.Model.ContainerId != "") {
+		self.stopAltaCntr()
+
+		// Remove the container. Ignore errors since node might be gone
+		var resp altaspec.ReqSuccess
+		err := nodeCtrler.NodeDeleteReq(self.Model.CurrNode, "/alta/"+self.AltaId, &resp)
+		if err != nil {
+			log.Errorf("Error removing container %s from node %s. Err: %v",
+				self.Model.ContainerId, self.Model.CurrNode, err)
+		}
+	}
+
+	// Release cpu/memory allocated on the node
+	if self.Model.CurrNode != "" {
+		// Finished jobs have already released their resources
+		if !self.isJobDone() {
+			self.freeAltaResources()
+		}
+
+		// walk all volumes and Unmount it
+		for _, volume := range self.Model.Spec.Volumes {
+			log.Infof("Unmounting volume: %+v", volume)
+
+			err := volumesCtrler.UnmountVolume(volume)
+			if err != nil {
+				log.Errorf("Error unmounting volume. Err: %v", err)
+			}
+		}
+	}
+}
+
+// Release all resources scheduler allocated for the alta
+func (self *AltaActor) freeAltaResources() error {
+	// resource list
+	rsrcList := scheduler.AltaResourceList(&self.Model.Spec, self.Model.CurrNode)
+
+	// Free the resources
+	err := rsrcMgr.FreeResources(rsrcList)
+	if err != nil {
+		log.Errorf("Error freeing resources for alta %s. Err: %v", self.AltaId, err)
+		return err
+	}
+
+	self.Model.Spec.AllocatedResources = nil
+
+	return nil
+}
+
+// Return externally visible state of the alta
+func (self *AltaActor) altaState() *common.AltaState {
+	return &common.AltaState{
+		Spec:        self.Model.Spec,
+		CurrNode:    self.Model.CurrNode,
+		ContainerId: self.Model.ContainerId,
+		FsmState:    self.Model.Fsm.FsmState,
+
+		RestartCount: self.Model.RestartCount,
+		NumRetries:   self.Model.NumRetries,
+		LastError:    self.Model.LastError,
+		Ready:        self.Model.Ready,
+		ExitCode:     self.Model.ExitCode,
+
+		PendingReasons: self.Model.PendingReasons,
+		Events:         self.Model.Events,
+	}
+}
+
+// Publish a copy of the alta state for other goroutines
+func (self *AltaActor) publishState() {
+	state := altaSnapshot{
+		AltaState:   *self.altaState(),
+		Preemptible: self.Model.Fsm.IsValidEvent("preempt"),
+	}
+
+	self.stateMutex.Lock()
+	self.state = state
+	self.stateMutex.Unlock()
+}
+
+// Return the state published by the alta actor.
+// Safe to call from any goroutine
+func (self *AltaActor) snapshot() altaSnapshot {
+	self.stateMutex.RLock()
+	defer self.stateMutex.RUnlock()
+
+	return self.state
+}
+
 // Save alta container state to conf store
 func (self *AltaActor) saveModel() error {
 	storeKey := "alta/" + self.Model.Spec.AltaId
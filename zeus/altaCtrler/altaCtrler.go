@@ -7,22 +7,36 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/contiv/symphony/zeus/common"
 	"github.com/contiv/symphony/zeus/netCtrler"
 	"github.com/contiv/symphony/zeus/nodeCtrler"
+	"github.com/contiv/symphony/zeus/scheduler"
 
 	"github.com/contiv/objmodel/objdb"
 	"github.com/contiv/symphony/pkg/altaspec"
+	"github.com/contiv/symphony/pkg/libfsm"
 
 	log "github.com/Sirupsen/logrus"
 )
 
 // State of alta manager
 type AltaMgr struct {
+	mutex      sync.RWMutex          // Lock for alta and job DBs
 	altaDb     map[string]*AltaActor // Main DB of alta containers
 	altaNameDb map[string]*AltaActor // mapping from alta names to container
 	cdb        objdb.ObjdbApi        // persistence store
+
+	gcMutex  sync.Mutex            // Lock for orphan GC state
+	orphanDb map[string]time.Time  // Orphan containers and when we first saw them
+	gcAudit  []common.GcAuditEntry // Recent orphan GC actions
+
+	jobDb map[string]*JobActor // Run to completion jobs
+
+	gangMutex      sync.Mutex                // Lock for gang scheduling
+	gangPlacements map[string]*gangPlacement // Gang placements members havent picked up yet
 }
 
 var altaCtrl *AltaMgr
@@ -34,14 +48,21 @@ func NewAltaCtrler(cdb objdb.ObjdbApi) *AltaMgr {
 	// Create the mapping databases
 	altaCtrl.altaDb = make(map[string]*AltaActor)
 	altaCtrl.altaNameDb = make(map[string]*AltaActor)
+	altaCtrl.orphanDb = make(map[string]time.Time)
+	altaCtrl.jobDb = make(map[string]*JobActor)
+	altaCtrl.gangPlacements = make(map[string]*gangPlacement)
 
 	// Keep a ref to cdb
 	altaCtrl.cdb = cdb
 
+	// Let the scheduler know where altas are placed
+	scheduler.SetPlacementSource(altaCtrl.listPlacements)
+
 	return altaCtrl
 }
 
-// Generate a Unique Id for the Alta container
+// Generate a Unique Id for the Alta container.
+// Caller must hold the lock
 func (self *AltaMgr) genAltaId() string {
 
 	// Loop till we find an id that doesnt exist
@@ -70,6 +91,10 @@ func (self *AltaMgr) genAltaId() string {
 func buildAltaSpec(altaConfig *altaspec.AltaConfig, altaSpec *altaspec.AltaSpec) {
 	// Initialize the parameters
 	altaSpec.AltaName = altaConfig.Name
+	altaSpec.Kind = altaConfig.Kind
+	if altaSpec.Kind == "" {
+		altaSpec.Kind = "service"
+	}
 	altaSpec.Image = altaConfig.Image
 	altaSpec.Command = []string{altaConfig.Command}
 	altaSpec.EnvList = altaConfig.Environment
@@ -104,7 +129,72 @@ func buildAltaSpec(altaConfig *altaspec.AltaConfig, altaSpec *altaspec.AltaSpec)
 		altaSpec.Memory = int64(mem)
 	}
 
-	// Parse network option
+	// Set the volumes
+	altaSpec.Volumes = altaConfig.Volumes
+
+	// Set the restart policy
+	altaSpec.SchedPolicy.RestartPolicy = altaConfig.RestartPolicy
+	if altaSpec.SchedPolicy.RestartPolicy == "" {
+		altaSpec.SchedPolicy.RestartPolicy = "always"
+	}
+	altaSpec.SchedPolicy.NumRestart = altaConfig.NumRestart
+
+	// Set the scheduler policy
+	altaSpec.Labels = altaConfig.Labels
+	altaSpec.SchedPolicy.SchedulerName = altaConfig.Scheduler
+	altaSpec.SchedPolicy.SpreadKey = altaConfig.SpreadKey
+	altaSpec.SchedPolicy.Constraints = altaConfig.Constraints
+	altaSpec.SchedPolicy.Resources = altaConfig.Resources
+	altaSpec.SchedPolicy.Affinity = altaConfig.Affinity
+	altaSpec.SchedPolicy.AntiAffinity = altaConfig.AntiAffinity
+	if (altaSpec.SchedPolicy.SchedulerName == "spread") && (altaSpec.SchedPolicy.SpreadKey == "") {
+		altaSpec.SchedPolicy.SpreadKey = "zone"
+	}
+
+	// Set the priority. Priority class was validated already
+	altaSpec.SchedPolicy.Priority = altaConfig.Priority
+	altaSpec.SchedPolicy.PriorityClass = altaConfig.PriorityClass
+	altaSpec.SchedPolicy.Gang = altaConfig.Gang
+	altaSpec.SchedPolicy.GangSize = altaConfig.GangSize
+
+	// Set the disruption budget
+	altaSpec.SchedPolicy.MaxUnavailable = altaConfig.MaxUnavailable
+	if altaSpec.SchedPolicy.MaxUnavailable == 0 {
+		altaSpec.SchedPolicy.MaxUnavailable = 1
+	}
+	if altaConfig.PriorityClass != "" {
+		altaSpec.SchedPolicy.Priority, _ = scheduler.PriorityClassValue(altaConfig.PriorityClass)
+	}
+
+	// Set the health checks
+	altaSpec.LivenessProbe = altaConfig.LivenessProbe
+	altaSpec.ReadinessProbe = altaConfig.ReadinessProbe
+
+	// Set max retries for failed operations
+	altaSpec.SchedPolicy.MaxRetries = altaConfig.MaxRetries
+	if altaSpec.SchedPolicy.MaxRetries == 0 {
+		altaSpec.SchedPolicy.MaxRetries = defaultMaxRetries
+	}
+
+	// Default volumes to mount
+	/* Disable this for now
+	altaSpec.Volumes = []altaspec.AltaVolumeBind{
+		{
+			DatastoreType: "PersistentVolume",
+			DatastoreVolumeId: altaSpec.AltaId,
+			BindMountPoint: "/var/data",
+		},
+		{
+			DatastoreType:     "HostVolume",
+			DatastoreVolumeId: altaSpec.AltaId + ".log",
+			BindMountPoint:    "/var/log",
+		},
+	}
+	*/
+}
+
+// Create network endpoints for the alta
+func createAltaEndpoints(altaConfig *altaspec.AltaConfig, altaSpec *altaspec.AltaSpec) {
 	if len(altaConfig.Networks) == 0 {
 		netIf, err := netCtrler.CreateAltaEndpoint(altaSpec.AltaId, "default", 0)
 		if err != nil {
@@ -129,35 +219,185 @@ func buildAltaSpec(altaConfig *altaspec.AltaConfig, altaSpec *altaspec.AltaSpec)
 		altaSpec.Endpoints = netIfs
 	}
 
-	// Set the volumes
-	altaSpec.Volumes = altaConfig.Volumes
+}
 
-	// Default volumes to mount
-	/* Disable this for now
-	altaSpec.Volumes = []altaspec.AltaVolumeBind{
-		{
-			DatastoreType: "PersistentVolume",
-			DatastoreVolumeId: altaSpec.AltaId,
-			BindMountPoint: "/var/data",
-		},
-		{
-			DatastoreType:     "HostVolume",
-			DatastoreVolumeId: altaSpec.AltaId + ".log",
-			BindMountPoint:    "/var/log",
-		},
+// Validate user specified alta config
+func validateAltaConfig(altaConfig *altaspec.AltaConfig) error {
+	// Check alta kind
+	switch altaConfig.Kind {
+	case "", "service":
+	case "job":
+		if (altaConfig.Job.Completions < 0) || (altaConfig.Job.Parallelism < 0) ||
+			((altaConfig.Job.BackoffLimit != nil) && (*altaConfig.Job.BackoffLimit < 0)) {
+			log.Errorf("Invalid job parameters %+v", altaConfig.Job)
+			return errors.New("Invalid job parameters")
+		}
+	default:
+		log.Errorf("Invalid alta kind %s", altaConfig.Kind)
+		return errors.New("Invalid alta kind")
 	}
-	*/
+
+	// Check restart policy
+	switch altaConfig.RestartPolicy {
+	case "", "always", "never", "onFailure":
+	default:
+		log.Errorf("Invalid restart policy %s", altaConfig.RestartPolicy)
+		return errors.New("Invalid restart policy")
+	}
+
+	// Check scheduler policy
+	_, err := scheduler.Scheduler(altaConfig.Scheduler)
+	if err != nil {
+		return err
+	}
+
+	// Check gang parameters
+	if (altaConfig.Gang != "") && (altaConfig.GangSize <= 0) {
+		log.Errorf("Invalid size %d for gang %s", altaConfig.GangSize, altaConfig.Gang)
+		return errors.New("Invalid gang size")
+	}
+	if (altaConfig.Gang != "") && (altaConfig.Kind == "job") {
+		log.Errorf("Job altas can not be part of gang %s", altaConfig.Gang)
+		return errors.New("Jobs can not be gang scheduled")
+	}
+
+	// Check disruption budget
+	if altaConfig.MaxUnavailable < 0 {
+		log.Errorf("Invalid max unavailable %d", altaConfig.MaxUnavailable)
+		return errors.New("Invalid max unavailable")
+	}
+
+	// Check priority
+	_, err = scheduler.PriorityClassValue(altaConfig.PriorityClass)
+	if err != nil {
+		return err
+	}
+	if (altaConfig.PriorityClass != "") && (altaConfig.Priority != 0) {
+		log.Errorf("Both priority %d and priority class %s specified", altaConfig.Priority,
+			altaConfig.PriorityClass)
+		return errors.New("Specify either priority or priority class")
+	}
+
+	// Check node constraints
+	_, err = scheduler.ParseConstraints(nil, altaConfig.Constraints)
+	if err != nil {
+		log.Errorf("Error parsing constraints %v. Err: %v", altaConfig.Constraints, err)
+		return err
+	}
+
+	// Check additional resources
+	rsrcTypes := make(map[string]bool)
+	for _, rsrc := range altaConfig.Resources {
+		if (rsrc.Type == "") || (rsrc.Type == "cpu") || (rsrc.Type == "memory") {
+			log.Errorf("Invalid resource type %q. Use cpu and memory fields for those", rsrc.Type)
+			return errors.New("Invalid resource type")
+		}
+		if rsrcTypes[rsrc.Type] {
+			log.Errorf("Resource %s requested more than once", rsrc.Type)
+			return errors.New("Duplicate resource type")
+		}
+		if rsrc.NumRsrc <= 0 {
+			log.Errorf("Invalid number of resources %+v", rsrc)
+			return errors.New("Invalid number of resources")
+		}
+		rsrcTypes[rsrc.Type] = true
+	}
+
+	// Check affinity rules
+	for _, rule := range append(altaConfig.Affinity, altaConfig.AntiAffinity...) {
+		if len(rule.Selector) == 0 {
+			log.Errorf("Affinity rule %+v has empty selector", rule)
+			return errors.New("Affinity rule requires a selector")
+		}
+	}
+
+	// Check number of restarts
+	if altaConfig.NumRestart < 0 {
+		log.Errorf("Invalid number of restarts %d", altaConfig.NumRestart)
+		return errors.New("Invalid number of restarts")
+	}
+
+	// Check number of retries
+	if altaConfig.MaxRetries < 0 {
+		log.Errorf("Invalid number of retries %d", altaConfig.MaxRetries)
+		return errors.New("Invalid number of retries")
+	}
+
+	// Check health probes
+	err = validateAltaProbe(altaConfig.LivenessProbe)
+	if err != nil {
+		return err
+	}
+	err = validateAltaProbe(altaConfig.ReadinessProbe)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Validate a health check probe
+func validateAltaProbe(probe *altaspec.AltaProbe) error {
+	// probes are optional
+	if probe == nil {
+		return nil
+	}
+
+	switch probe.Type {
+	case "exec":
+		if len(probe.Command) == 0 {
+			log.Errorf("Exec probe requires a command: %+v", probe)
+			return errors.New("Exec probe requires a command")
+		}
+	case "http", "tcp":
+		if (probe.Port <= 0) || (probe.Port > 65535) {
+			log.Errorf("Invalid port in probe: %+v", probe)
+			return errors.New("Invalid probe port")
+		}
+	default:
+		log.Errorf("Invalid probe type %s", probe.Type)
+		return errors.New("Invalid probe type")
+	}
+
+	// Check timers and thresholds
+	if (probe.InitialDelay < 0) || (probe.Interval < 0) || (probe.Timeout < 0) ||
+		(probe.FailureThreshold < 0) || (probe.SuccessThreshold < 0) {
+		log.Errorf("Invalid probe parameters: %+v", probe)
+		return errors.New("Invalid probe parameters")
+	}
+
+	return nil
 }
 
 // Create a new Alta container
 func (self *AltaMgr) CreateAlta(altaConfig *altaspec.AltaConfig) error {
+	// Validate the config
+	err := validateAltaConfig(altaConfig)
+	if err != nil {
+		return &common.ConfigError{Err: err}
+	}
+
+	// Jobs are managed by the job controller
+	if altaConfig.Kind == "job" {
+		return self.CreateJob(altaConfig)
+	}
+
+	_, err = self.createAlta(altaConfig, "")
+	return err
+}
+
+// Create an alta actor and kick off scheduling
+func (self *AltaMgr) createAlta(altaConfig *altaspec.AltaConfig, jobName string) (*AltaActor, error) {
 	var altaSpec altaspec.AltaSpec
 
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
 	// Check if a name was specified and a container of this name already exists
 	if altaConfig.Name != "" {
 		if self.altaNameDb[altaConfig.Name] != nil {
 			log.Errorf("Error: Alta Container %s already exists", altaConfig.Name)
-			return errors.New("Alta container already exists")
+			return nil, errors.New("Alta container already exists")
 		}
 	}
 
@@ -168,12 +408,19 @@ func (self *AltaMgr) CreateAlta(altaConfig *altaspec.AltaConfig) error {
 
 	// Initialize the parameters
 	buildAltaSpec(altaConfig, &altaSpec)
+	createAltaEndpoints(altaConfig, &altaSpec)
+	altaSpec.JobName = jobName
 
 	// Create a new container
 	alta, err := NewAlta(&altaSpec)
 	if err != nil {
 		log.Errorf("Error creating alta: %+v. Err: %v", altaConfig, err)
-		return err
+
+		// Release the addresses we allocated for it
+		for _, endpoint := range altaSpec.Endpoints {
+			netCtrler.DeleteAltaEndpoint(altaSpec.AltaId, endpoint.NetworkName)
+		}
+		return nil, err
 	}
 
 	// Save the container in the DB
@@ -185,22 +432,86 @@ func (self *AltaMgr) CreateAlta(altaConfig *altaspec.AltaConfig) error {
 	// post schedule event
 	alta.AltaEvent("schedule")
 
-	return nil
+	return alta, nil
+}
+
+// Simulate scheduling of the altas without creating them
+func (self *AltaMgr) SimulateAltas(altaConfigs []altaspec.AltaConfig) ([]*common.SimulateResult, error) {
+	var specs []*altaspec.AltaSpec
+
+	for idx := range altaConfigs {
+		altaConfig := &altaConfigs[idx]
+
+		// Check the config like a real create would
+		err := validateAltaConfig(altaConfig)
+		if err != nil {
+			log.Errorf("Invalid alta config %+v. Err: %v", altaConfig, err)
+			return nil, &common.ConfigError{Err: err}
+		}
+
+		// Build the spec without creating any endpoints
+		var altaSpec altaspec.AltaSpec
+		altaSpec.AltaId = fmt.Sprintf("simulate-%d", idx)
+		buildAltaSpec(altaConfig, &altaSpec)
+		if altaSpec.AltaName == "" {
+			altaSpec.AltaName = altaSpec.AltaId
+		}
+
+		specs = append(specs, &altaSpec)
+	}
+
+	return scheduler.Simulate(specs), nil
+}
+
+// DeleteAlta stops the alta container and releases all its resources
+func (self *AltaMgr) DeleteAlta(altaId string) error {
+	// Ask the actor to tear itself down and wait for the result
+	return self.AltaUserEvent(altaId, "delete")
+}
+
+// Find an alta actor by id. Returns nil if it doesnt exist
+func (self *AltaMgr) findAlta(altaId string) *AltaActor {
+	self.mutex.RLock()
+	defer self.mutex.RUnlock()
+
+	return self.altaDb[altaId]
+}
+
+// Return all alta actors
+func (self *AltaMgr) listAltaActors() []*AltaActor {
+	self.mutex.RLock()
+	defer self.mutex.RUnlock()
+
+	altaList := make([]*AltaActor, 0, len(self.altaDb))
+	for _, alta := range self.altaDb {
+		altaList = append(altaList, alta)
+	}
+
+	return altaList
+}
+
+// Remove a deleted alta actor from the DBs
+func (self *AltaMgr) removeAlta(alta *AltaActor) {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	delete(self.altaDb, alta.AltaId)
+	if alta.Model.Spec.AltaName != "" {
+		delete(self.altaNameDb, alta.Model.Spec.AltaName)
+	}
+	scheduler.ForgetAlta(alta.AltaId)
+
+	log.Infof("Removed alta: %s", alta.AltaId)
 }
 
 // Return a list of all alta containers
 func (self *AltaMgr) ListAlta() []*common.AltaState {
 	altaList := make([]*common.AltaState, 0)
 
-	// Append each alta actor's model
-	for _, alta := range self.altaDb {
-		astate := common.AltaState{
-			Spec:        alta.Model.Spec,
-			CurrNode:    alta.Model.CurrNode,
-			ContainerId: alta.Model.ContainerId,
-			FsmState:    alta.Model.Fsm.FsmState,
-		}
-		altaList = append(altaList, &astate)
+	// Append each alta actor's state
+	for _, alta := range self.listAltaActors() {
+		state := alta.snapshot()
+		altaList = append(altaList, &state.AltaState)
 	}
 
 	log.Debugf("Returning alta list: %+v", altaList)
@@ -208,37 +519,180 @@ func (self *AltaMgr) ListAlta() []*common.AltaState {
 	return altaList
 }
 
+// Return labels and priority of each alta. Scheduler finds the node
+// each alta is placed on from the resources allocated to it
+func (self *AltaMgr) listPlacements() []scheduler.AltaPlacement {
+	var placements []scheduler.AltaPlacement
+
+	for _, alta := range self.listAltaActors() {
+		state := alta.snapshot()
+		placements = append(placements, scheduler.AltaPlacement{
+			AltaId:   alta.AltaId,
+			Labels:   state.Spec.Labels,
+			JobName:  state.Spec.JobName,
+			Priority: state.Spec.SchedPolicy.Priority,
+
+			Preemptible: state.Preemptible,
+		})
+	}
+
+	return placements
+}
+
+// Return the state of an alta container
+func (self *AltaMgr) GetAlta(altaId string) (*common.AltaState, error) {
+	// check for errors
+	alta := self.findAlta(altaId)
+	if alta == nil {
+		return nil, common.ErrAltaNotFound
+	}
+
+	state := alta.snapshot()
+	return &state.AltaState, nil
+}
+
 // AltaEvent trigger an event on the alta actor
 func (self *AltaMgr) AltaEvent(altaId string, event string) error {
+	return self.altaEventData(altaId, event, nil)
+}
+
+// Trigger an event with event data on the alta actor
+func (self *AltaMgr) altaEventData(altaId string, event string, eventData interface{}) error {
 	// check for errors
-	if self.altaDb[altaId] == nil {
+	alta := self.findAlta(altaId)
+	if alta == nil {
 		return errors.New("Alta not found")
 	}
 
 	// post the event
-	alta := self.altaDb[altaId]
-	alta.AltaEvent(event)
+	alta.EventChan <- libfsm.Event{event, eventData}
 
 	return nil
 }
 
+// AltaUserEvent triggers a user requested event after validating it
+func (self *AltaMgr) AltaUserEvent(altaId string, event string) error {
+	return self.postUserEvent(altaId, event, &userEventReq{})
+}
+
+// MigrateAlta moves a running alta from its node to the target node
+func (self *AltaMgr) MigrateAlta(altaId string, toNode string) error {
+	return self.postUserEvent(altaId, "migrate", &userEventReq{toNode: toNode})
+}
+
+// Post a user event and wait for the actor to process it
+func (self *AltaMgr) postUserEvent(altaId string, event string, req *userEventReq) error {
+	// check for errors
+	alta := self.findAlta(altaId)
+	if alta == nil {
+		log.Errorf("Alta %s not found", altaId)
+		return common.ErrAltaNotFound
+	}
+
+	// Actor checks if the event is allowed in its current state
+	req.replyChan = make(chan error, 1)
+	select {
+	case alta.EventChan <- libfsm.Event{event, req}:
+	case <-alta.exitChan:
+		return common.ErrAltaNotFound
+	}
+
+	select {
+	case err := <-req.replyChan:
+		return err
+	case <-alta.exitChan:
+		// Actor replies before it exits. See if it processed our event
+		select {
+		case err := <-req.replyChan:
+			return err
+		default:
+			log.Errorf("Alta %s was deleted before processing event %s", altaId, event)
+			return common.ErrAltaNotFound
+		}
+	case <-time.After(userEventTimeout):
+		log.Errorf("Timed out waiting for alta %s to process event %s", altaId, event)
+		return errors.New("Timed out processing the event")
+	}
+}
+
+// Evict the victim altas so that a higher priority alta can use their resources.
+// Doesnt wait for the victims, so that preempting alta keeps processing its events
+func (self *AltaMgr) preemptAltas(preemptor *AltaActor, victims []string) {
+	for _, victimId := range victims {
+		victim := self.findAlta(victimId)
+		if victim == nil {
+			log.Warnf("Victim alta %s not found while preempting for %s", victimId, preemptor.AltaId)
+			continue
+		}
+
+		// Ask the victim to leave its node
+		req := preemptReq{
+			preemptorId: preemptor.AltaId,
+			priority:    preemptor.Model.Spec.SchedPolicy.Priority,
+			doneChan:    make(chan struct{}),
+		}
+		go preemptVictim(victim, &req)
+	}
+}
+
+// Post the preempt request to the victim and count the eviction once it left its node
+func preemptVictim(victim *AltaActor, req *preemptReq) {
+	select {
+	case victim.EventChan <- libfsm.Event{"preempt", req}:
+	case <-victim.exitChan:
+		log.Warnf("Victim alta %s was deleted before preempting for %s", victim.AltaId, req.preemptorId)
+		return
+	}
+
+	select {
+	case <-req.doneChan:
+	case <-victim.exitChan:
+		// Victim finishes the request before it exits. See if it got to ours
+		select {
+		case <-req.doneChan:
+		default:
+			log.Warnf("Victim alta %s was deleted before preempting for %s", victim.AltaId, req.preemptorId)
+			return
+		}
+	case <-time.After(preemptTimeout):
+		log.Errorf("Timed out waiting for alta %s to be preempted", victim.AltaId)
+		return
+	}
+
+	if req.err != nil {
+		log.Errorf("Alta %s was not preempted for %s. Err: %v", victim.AltaId, req.preemptorId, req.err)
+		return
+	}
+
+	// Count the eviction against the rate limit
+	scheduler.RecordEviction()
+}
+
 // Diff the alta list we got from a node and what we expect
 func (self *AltaMgr) ReconcileNode(nodeAddr string, altaList []altaspec.AltaContext) error {
 	// Get the list of altas we expect on this node
 	expAltaList := self.listAltaForNode(nodeAddr)
 
 	// convert the list to maps indexed by container id
-	expContMap := make(map[string]*AltaModel)
-	expAltaMap := make(map[string]*AltaModel)
+	expContMap := make(map[string]*common.AltaState)
+	expAltaMap := make(map[string]*common.AltaState)
 	contMap := make(map[string]*altaspec.AltaContext)
 	altaMap := make(map[string]*altaspec.AltaContext)
+	exitMap := make(map[string]*altaspec.AltaContext)
 	for _, alta := range expAltaList {
 		expContMap[alta.ContainerId] = alta
 		expAltaMap[alta.Spec.AltaId] = alta
 	}
-	for _, alta := range altaList {
-		contMap[alta.ContainerId] = &alta
-		altaMap[alta.AltaId] = &alta
+	for i := range altaList {
+		alta := &altaList[i]
+
+		// Only running containers count for failure detection
+		if !alta.Running {
+			exitMap[alta.ContainerId] = alta
+			continue
+		}
+		contMap[alta.ContainerId] = alta
+		altaMap[alta.AltaId] = alta
 	}
 
 	// check if anything we expect is missing or changed
@@ -274,25 +728,49 @@ func (self *AltaMgr) ReconcileNode(nodeAddr string, altaList []altaspec.AltaCont
 			}
 		}
 
-		// See if container is completely missing from the list
-		if (altaMap[alta.Spec.AltaId] == nil) && (contMap[alta.ContainerId] == nil) {
-			log.Infof("Alta %+v needs to be restarted", alta)
+		// See if container exited or is completely missing from the list
+		if (altaMap[alta.Spec.AltaId] == nil) && (contMap[alta.ContainerId] == nil) &&
+			(alta.FsmState == "running") {
+			// Check if the container exited cleanly
+			exitCtx := exitMap[alta.ContainerId]
+			if exitCtx != nil {
+				self.altaEventData(alta.Spec.AltaId, "nodeStatus",
+					&nodeStatus{exited: true, exitCode: exitCtx.ExitCode})
+			}
+			if (exitCtx != nil) && (exitCtx.ExitCode == 0) && ((alta.Spec.Kind == "job") ||
+				(alta.Spec.SchedPolicy.RestartPolicy == "onFailure")) {
+				log.Infof("Alta %s completed successfully", alta.Spec.AltaId)
+
+				// Queue complete event to alta.
+				self.AltaEvent(alta.Spec.AltaId, "complete")
+			} else {
+				log.Infof("Alta %+v needs to be restarted", alta)
 
-			// Queue failure event to alta.
-			self.AltaEvent(alta.Spec.AltaId, "failure")
+				// Queue failure event to alta.
+				self.AltaEvent(alta.Spec.AltaId, "failure")
+			}
 		}
-	}
 
-	// Check if there is a container that we dont expect
-	for _, alta := range altaList {
-		if alta.AltaId == "" && expContMap[alta.ContainerId] == nil {
-			log.Infof("Node has unexpected alta: %+v. Kill it", alta)
-			// FIXME:
-		} else if alta.AltaId != "" && expAltaMap[alta.AltaId] == nil {
-			log.Infof("Node has unexpected altaId %+v", alta)
+		// Check health probe results
+		if altaMap[alta.Spec.AltaId] != nil {
+			altaCtx := altaMap[alta.Spec.AltaId]
+			if alta.Ready != altaCtx.Ready {
+				self.altaEventData(alta.Spec.AltaId, "nodeStatus", &nodeStatus{ready: altaCtx.Ready})
+			}
+
+			// Restart the container if liveness probe failed
+			if altaCtx.LivenessFailed && (alta.FsmState == "running") {
+				log.Infof("Liveness probe failed for alta %s. Restarting", alta.Spec.AltaId)
+
+				// Queue failure event to alta.
+				self.AltaEvent(alta.Spec.AltaId, "failure")
+			}
 		}
 	}
 
+	// Garbage collect containers that we dont expect
+	self.gcOrphanContainers(nodeAddr, altaList, expContMap)
+
 	return nil
 }
 
@@ -310,13 +788,14 @@ func (self *AltaMgr) NodeDownEvent(nodeAddr string) error {
 }
 
 // Return a list of all alta containers
-func (self *AltaMgr) listAltaForNode(nodeAddr string) []*AltaModel {
-	var altaList []*AltaModel
+func (self *AltaMgr) listAltaForNode(nodeAddr string) []*common.AltaState {
+	var altaList []*common.AltaState
 
 	// Walk thru all altas and see if they match this node
-	for _, alta := range self.altaDb {
-		if alta.Model.CurrNode == nodeAddr {
-			altaList = append(altaList, &alta.Model)
+	for _, alta := range self.listAltaActors() {
+		state := alta.snapshot()
+		if state.CurrNode == nodeAddr {
+			altaList = append(altaList, &state.AltaState)
 		}
 	}
 
@@ -343,25 +822,34 @@ func (self *AltaMgr) RestoreAltaActors() error {
 		}
 
 		// Create an actor for the alta container
-		alta, err := NewAlta(&model.Spec)
-		if err != nil {
-			log.Errorf("Error restoring Alta %s. Err: %v", model.Spec.AltaId, err)
-			return err
-		}
+		alta := newAlta(&model.Spec)
 
 		// Restore state
 		alta.Model.CurrNode = model.CurrNode
 		alta.Model.ContainerId = model.ContainerId
 		alta.Model.Fsm.FsmState = model.Fsm.FsmState
+		alta.Model.RestartCount = model.RestartCount
+		alta.Model.NextRestart = model.NextRestart
+		alta.Model.LastRestart = model.LastRestart
+		alta.Model.NumRetries = model.NumRetries
+		alta.Model.LastError = model.LastError
+		alta.Model.StateDeadline = model.StateDeadline
+		alta.Model.ExitCode = model.ExitCode
+		alta.Model.PendingReasons = model.PendingReasons
+		alta.Model.Events = model.Events
+		alta.start()
 
 		// Save the container in the DB
+		self.mutex.Lock()
 		self.altaDb[alta.AltaId] = alta
 		if model.Spec.AltaName != "" {
 			self.altaNameDb[model.Spec.AltaName] = alta
 		}
+		self.mutex.Unlock()
 
 		log.Infof("Restored alta: %#v", alta)
 	}
 
-	return nil
+	// Restore jobs after all altas are restored
+	return self.restoreJobActors()
 }
@@ -0,0 +1,116 @@
+package altaCtrler
+
+// This file implements garbage collection of orphan containers
+
+import (
+	"strings"
+	"time"
+
+	"github.com/contiv/symphony/zeus/common"
+	"github.com/contiv/symphony/zeus/nodeCtrler"
+
+	"github.com/contiv/symphony/pkg/altaspec"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+const (
+	orphanGracePeriod = time.Minute * 5 // How long to wait before removing an orphan
+	maxGcAuditEntries = 100             // Number of audit entries to keep in memory
+)
+
+// Handle containers on a node that we dont expect
+func (self *AltaMgr) gcOrphanContainers(nodeAddr string, altaList []altaspec.AltaContext,
+	expContMap map[string]*common.AltaState) {
+	self.gcMutex.Lock()
+	defer self.gcMutex.Unlock()
+
+	seenOrphans := make(map[string]bool)
+
+	// Check if there is a container that we dont expect
+	for _, alta := range altaList {
+		if expContMap[alta.ContainerId] != nil {
+			continue
+		}
+
+		// Leave containers not created by us alone
+		if !alta.Owned {
+			log.Debugf("Ignoring container %s not owned by symphony", alta.ContainerId)
+			continue
+		}
+
+		orphanKey := nodeAddr + "/" + alta.ContainerId
+		seenOrphans[orphanKey] = true
+
+		// Remember when we first saw it
+		firstSeen, ok := self.orphanDb[orphanKey]
+		if !ok {
+			log.Infof("Node %s has unexpected container: %+v", nodeAddr, alta)
+			self.orphanDb[orphanKey] = time.Now()
+			self.gcAuditLog(nodeAddr, alta, "detected", "container not expected by master")
+			continue
+		}
+
+		// Wait for grace period to expire
+		if time.Since(firstSeen) < orphanGracePeriod {
+			continue
+		}
+
+		// Ask the node to remove it
+		var resp altaspec.ReqSuccess
+		err := nodeCtrler.NodeDeleteReq(nodeAddr, "/container/"+alta.ContainerId, &resp)
+		if err != nil {
+			log.Errorf("Error removing orphan container %s on node %s. Err: %v",
+				alta.ContainerId, nodeAddr, err)
+			self.gcAuditLog(nodeAddr, alta, "removeFailed", err.Error())
+			continue
+		}
+
+		self.gcAuditLog(nodeAddr, alta, "removed", "grace period expired")
+		delete(self.orphanDb, orphanKey)
+	}
+
+	// Forget about orphans that went away on their own
+	nodePrefix := nodeAddr + "/"
+	for orphanKey := range self.orphanDb {
+		if strings.HasPrefix(orphanKey, nodePrefix) && !seenOrphans[orphanKey] {
+			delete(self.orphanDb, orphanKey)
+		}
+	}
+}
+
+// Record a GC action in the audit log
+func (self *AltaMgr) gcAuditLog(nodeAddr string, alta altaspec.AltaContext, action, reason string) {
+	entry := common.GcAuditEntry{
+		Time:        time.Now(),
+		NodeAddr:    nodeAddr,
+		ContainerId: alta.ContainerId,
+		AltaId:      alta.AltaId,
+		Action:      action,
+		Reason:      reason,
+	}
+
+	log.WithFields(log.Fields{
+		"node":      nodeAddr,
+		"container": alta.ContainerId,
+		"altaId":    alta.AltaId,
+		"action":    action,
+	}).Infof("Orphan container GC: %s", reason)
+
+	// Keep a bounded list of entries
+	self.gcAudit = append(self.gcAudit, entry)
+	if len(self.gcAudit) > maxGcAuditEntries {
+		self.gcAudit = self.gcAudit[len(self.gcAudit)-maxGcAuditEntries:]
+	}
+}
+
+// Return the orphan container GC audit log
+func (self *AltaMgr) GcAuditLog() []common.GcAuditEntry {
+	self.gcMutex.Lock()
+	defer self.gcMutex.Unlock()
+
+	auditList := make([]common.GcAuditEntry, len(self.gcAudit))
+	copy(auditList, self.gcAudit)
+
+	return auditList
+}
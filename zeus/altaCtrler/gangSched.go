@@ -0,0 +1,126 @@
+package altaCtrler
+
+// This file implements gang scheduling. Altas of a gang are placed all or
+// nothing. Whichever member is scheduled first places the whole gang and
+// hands over the placements to other members
+
+import (
+	"fmt"
+
+	"github.com/contiv/symphony/zeus/scheduler"
+
+	"github.com/contiv/symphony/pkg/altaspec"
+	"github.com/contiv/symphony/pkg/rsrcMgr"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// Placement picked for a gang member that it hasnt picked up yet
+type gangPlacement struct {
+	nodeAddr string            // Node picked for the member
+	spec     altaspec.AltaSpec // Spec with the resources allocated for the member
+}
+
+// Schedule the gang the alta belongs to. Returns the node for the alta.
+// Other members pick up their placements when they are scheduled next
+func (self *AltaMgr) scheduleGang(alta *AltaActor) (string, error) {
+	gangName := alta.Model.Spec.SchedPolicy.Gang
+
+	self.gangMutex.Lock()
+	defer self.gangMutex.Unlock()
+
+	// See if another member placed us already
+	if placement := self.gangPlacements[alta.AltaId]; placement != nil {
+		delete(self.gangPlacements, alta.AltaId)
+		alta.Model.Spec.AllocatedResources = placement.spec.AllocatedResources
+		alta.setGangNode(placement.nodeAddr)
+
+		return placement.nodeAddr, nil
+	}
+
+	// Find the members that still need a node. Other members are read from
+	// their published state since they run in their own goroutines
+	var members []*AltaActor
+	var unplaced []altaspec.AltaSpec
+	for _, member := range self.listAltaActors() {
+		state := member.snapshot()
+		if member == alta {
+			state.AltaState = *alta.altaState()
+		}
+		if state.Spec.SchedPolicy.Gang != gangName {
+			continue
+		}
+
+		members = append(members, member)
+		if (state.CurrNode == "") && (self.gangPlacements[member.AltaId] == nil) {
+			unplaced = append(unplaced, state.Spec)
+		}
+	}
+
+	// Wait till all members are created
+	gangSize := alta.Model.Spec.SchedPolicy.GangSize
+	if len(members) < gangSize {
+		log.Infof("Gang %s has %d of %d members. Not scheduling alta %s yet", gangName,
+			len(members), gangSize, alta.AltaId)
+		return "", &scheduler.ScheduleError{
+			Message: fmt.Sprintf("Waiting for gang members, %d of %d created", len(members), gangSize),
+		}
+	}
+
+	// Place all of them or none of them
+	specs := make([]*altaspec.AltaSpec, len(unplaced))
+	for idx := range unplaced {
+		specs[idx] = &unplaced[idx]
+	}
+	nodeAddrs, err := scheduler.ScheduleGang(specs)
+	if err != nil {
+		log.Errorf("Error scheduling gang %s. Err: %v", gangName, err)
+		return "", err
+	}
+
+	// Hand over the placements to other members
+	var nodeAddr string
+	for idx, spec := range specs {
+		if spec.AltaId == alta.AltaId {
+			nodeAddr = nodeAddrs[idx]
+			alta.Model.Spec.AllocatedResources = spec.AllocatedResources
+			alta.setGangNode(nodeAddr)
+			continue
+		}
+
+		self.gangPlacements[spec.AltaId] = &gangPlacement{
+			nodeAddr: nodeAddrs[idx],
+			spec:     *spec,
+		}
+		self.AltaEvent(spec.AltaId, "schedule")
+	}
+
+	log.Infof("Scheduled gang %s with %d altas", gangName, len(unplaced))
+
+	return nodeAddr, nil
+}
+
+// Save the node picked for a gang member and publish it right away, so that
+// members scheduled next dont think it still needs a node
+func (self *AltaActor) setGangNode(nodeAddr string) {
+	self.Model.CurrNode = nodeAddr
+	self.publishState()
+}
+
+// Release the placement of a gang member that never picked it up
+func (self *AltaMgr) cancelGangPlacement(altaId string) {
+	self.gangMutex.Lock()
+	defer self.gangMutex.Unlock()
+
+	placement := self.gangPlacements[altaId]
+	if placement == nil {
+		return
+	}
+	delete(self.gangPlacements, altaId)
+
+	// Free the resources allocated for it
+	err := rsrcMgr.FreeResources(scheduler.AltaResourceList(&placement.spec, placement.nodeAddr))
+	if err != nil {
+		log.Errorf("Error freeing gang placement for alta %s. Err: %v", altaId, err)
+	}
+}
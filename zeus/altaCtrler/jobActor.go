@@ -0,0 +1,326 @@
+package altaCtrler
+
+// This file implements run to completion jobs. Each job spawns child altas
+// till required number of them complete successfully
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/contiv/symphony/zeus/common"
+
+	"github.com/contiv/symphony/pkg/altaspec"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+const (
+	jobBackoffBase = time.Second * 10 // Initial delay after a failed alta
+	jobBackoffMax  = time.Minute * 5  // Max delay between failed altas
+)
+
+// Number of failures before a job is failed, if user did not set it
+const defaultJobBackoffLimit = 6
+
+// Job state to be persisted
+type JobModel struct {
+	JobName     string              // Name of the job
+	Config      altaspec.AltaConfig // Template for the child altas
+	State       string              // running, complete or failed
+	Succeeded   int                 // Number of altas that completed successfully
+	Failed      int                 // Number of altas that failed
+	Active      []string            // Alta ids that are still running
+	NextIndex   int                 // Index for the next child alta name
+	NextSpawn   time.Time           // Dont create new altas before this time
+	CreatedTime time.Time           // When the job was created
+}
+
+// Job controller
+type JobActor struct {
+	Model    JobModel      // State of the job
+	ticker   *time.Ticker  // Ticker to check on child altas
+	stopChan chan struct{} // Channel to stop the job loop
+
+	mutex sync.Mutex // Lock for the job model
+}
+
+// Create a job actor and start its run loop
+func NewJob(model *JobModel) *JobActor {
+	job := new(JobActor)
+	job.Model = *model
+
+	job.ticker = time.NewTicker(time.Second * 5)
+	job.stopChan = make(chan struct{})
+
+	// Kick off the job runloop
+	go job.runLoop()
+
+	return job
+}
+
+// Main run loop for the job
+func (self *JobActor) runLoop() {
+	for {
+		select {
+		case <-self.ticker.C:
+			self.mutex.Lock()
+			if self.Model.State == "running" {
+				self.syncJob()
+			}
+			self.mutex.Unlock()
+		case <-self.stopChan:
+			self.ticker.Stop()
+			return
+		}
+	}
+}
+
+// Check on child altas and create new ones as required.
+// Caller must hold the job lock
+func (self *JobActor) syncJob() {
+	changed := false
+	jobSpec := self.Model.Config.Job
+
+	// Check the state of active altas
+	var active []string
+	for _, altaId := range self.Model.Active {
+		alta := altaCtrl.findAlta(altaId)
+		if alta == nil {
+			log.Warnf("Alta %s of job %s was deleted", altaId, self.Model.JobName)
+			self.Model.Failed++
+			changed = true
+			continue
+		}
+
+		switch alta.snapshot().FsmState {
+		case "succeeded":
+			self.Model.Succeeded++
+			changed = true
+		case "failed", "error":
+			self.Model.Failed++
+			changed = true
+
+			// Backoff before creating another alta
+			backoff := jobBackoffMax
+			if self.Model.Failed < 6 {
+				backoff = jobBackoffBase * (1 << uint(self.Model.Failed-1))
+				if backoff > jobBackoffMax {
+					backoff = jobBackoffMax
+				}
+			}
+			self.Model.NextSpawn = time.Now().Add(backoff)
+		default:
+			active = append(active, altaId)
+		}
+	}
+	self.Model.Active = active
+
+	// Check if the job is done
+	if self.Model.Succeeded >= jobSpec.Completions {
+		log.Infof("Job %s completed", self.Model.JobName)
+		self.Model.State = "complete"
+		self.saveModel()
+		return
+	}
+	if self.Model.Failed > *jobSpec.BackoffLimit {
+		log.Errorf("Job %s failed %d times. Giving up", self.Model.JobName, self.Model.Failed)
+		self.Model.State = "failed"
+
+		// Stop any altas that are still running
+		for _, altaId := range self.Model.Active {
+			altaCtrl.AltaEvent(altaId, "delete")
+		}
+		self.Model.Active = nil
+
+		self.saveModel()
+		return
+	}
+
+	// Create more altas if required
+	for (len(self.Model.Active) < jobSpec.Parallelism) &&
+		(self.Model.Succeeded+len(self.Model.Active) < jobSpec.Completions) &&
+		time.Now().After(self.Model.NextSpawn) {
+		altaConfig := self.Model.Config
+		altaConfig.Name = fmt.Sprintf("%s-%d", self.Model.JobName, self.Model.NextIndex)
+		altaConfig.RestartPolicy = "never"
+		self.Model.NextIndex++
+
+		alta, err := altaCtrl.createAlta(&altaConfig, self.Model.JobName)
+		if err != nil {
+			log.Errorf("Error creating alta for job %s. Err: %v", self.Model.JobName, err)
+			break
+		}
+
+		self.Model.Active = append(self.Model.Active, alta.AltaId)
+		changed = true
+	}
+
+	if changed {
+		self.saveModel()
+	}
+}
+
+// Return externally visible state of the job
+func (self *JobActor) jobState() *common.JobState {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	return &common.JobState{
+		JobName:     self.Model.JobName,
+		State:       self.Model.State,
+		Job:         self.Model.Config.Job,
+		Succeeded:   self.Model.Succeeded,
+		Failed:      self.Model.Failed,
+		Active:      self.Model.Active,
+		CreatedTime: self.Model.CreatedTime,
+	}
+}
+
+// Save job state to conf store
+func (self *JobActor) saveModel() error {
+	storeKey := "job/" + self.Model.JobName
+
+	// Save it to conf store
+	err := altaCtrl.cdb.SetObj(storeKey, self.Model)
+	if err != nil {
+		log.Errorf("Error storing object %+v. Err: %v", self.Model, err)
+		return err
+	}
+
+	return nil
+}
+
+// Create a new run to completion job
+func (self *AltaMgr) CreateJob(altaConfig *altaspec.AltaConfig) error {
+	// Jobs need a name
+	if altaConfig.Name == "" {
+		log.Errorf("Job name is required")
+		return errors.New("Job name is required")
+	}
+
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	if self.jobDb[altaConfig.Name] != nil {
+		log.Errorf("Error: Job %s already exists", altaConfig.Name)
+		return errors.New("Job already exists")
+	}
+
+	// Set the defaults
+	jobModel := JobModel{
+		JobName:     altaConfig.Name,
+		Config:      *altaConfig,
+		State:       "running",
+		CreatedTime: time.Now(),
+	}
+	if jobModel.Config.Job.Completions == 0 {
+		jobModel.Config.Job.Completions = 1
+	}
+	if jobModel.Config.Job.Parallelism == 0 {
+		jobModel.Config.Job.Parallelism = 1
+	}
+	if jobModel.Config.Job.BackoffLimit == nil {
+		backoffLimit := defaultJobBackoffLimit
+		jobModel.Config.Job.BackoffLimit = &backoffLimit
+	}
+
+	log.Infof("Creating job: %+v", jobModel)
+
+	// Create the job actor
+	job := NewJob(&jobModel)
+	job.saveModel()
+
+	self.jobDb[jobModel.JobName] = job
+
+	return nil
+}
+
+// Delete a job and all its altas
+func (self *AltaMgr) DeleteJob(jobName string) error {
+	self.mutex.Lock()
+	job := self.jobDb[jobName]
+	delete(self.jobDb, jobName)
+	self.mutex.Unlock()
+
+	if job == nil {
+		log.Errorf("Job %s not found", jobName)
+		return common.ErrJobNotFound
+	}
+
+	// Stop the job loop
+	close(job.stopChan)
+
+	// Wait for a sync in progress to finish and make sure no more altas are created
+	job.mutex.Lock()
+	defer job.mutex.Unlock()
+	job.Model.State = "deleted"
+
+	// Delete all altas belonging to the job
+	for _, alta := range self.listAltaActors() {
+		if alta.snapshot().Spec.JobName == jobName {
+			alta.AltaEvent("delete")
+		}
+	}
+
+	// Remove it from conf store
+	storeKey := "job/" + jobName
+	err := self.cdb.DelObj(storeKey)
+	if err != nil {
+		log.Errorf("Error deleting object %s. Err: %v", storeKey, err)
+	}
+
+	return nil
+}
+
+// Return a list of all jobs
+func (self *AltaMgr) ListJobs() []*common.JobState {
+	jobList := make([]*common.JobState, 0)
+
+	// Dont hold the DB lock while waiting for the job lock
+	self.mutex.RLock()
+	jobs := make([]*JobActor, 0, len(self.jobDb))
+	for _, job := range self.jobDb {
+		jobs = append(jobs, job)
+	}
+	self.mutex.RUnlock()
+
+	for _, job := range jobs {
+		jobList = append(jobList, job.jobState())
+	}
+
+	return jobList
+}
+
+// Restore job state from cdb
+func (self *AltaMgr) restoreJobActors() error {
+	// Get the list of elements
+	jsonArr, err := self.cdb.ListDir("job")
+	if err != nil {
+		log.Errorf("Error restoring job state")
+		return err
+	}
+
+	// Loop thru each job model
+	for _, elemStr := range jsonArr {
+		// Parse the json model
+		var model JobModel
+		err = json.Unmarshal([]byte(elemStr), &model)
+		if err != nil {
+			log.Errorf("Error parsing object %s, Err %v", elemStr, err)
+			return err
+		}
+
+		// Create an actor for the job
+		job := NewJob(&model)
+		self.mutex.Lock()
+		self.jobDb[model.JobName] = job
+		self.mutex.Unlock()
+
+		log.Infof("Restored job: %+v", model)
+	}
+
+	return nil
+}
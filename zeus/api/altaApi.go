@@ -1,12 +1,19 @@
 package api
 
 import (
+	"bufio"
+	"bytes"
 	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net"
 	"net/http"
 
 	"github.com/contiv/symphony/pkg/altaspec"
+	"github.com/contiv/symphony/zeus/nodeCtrler"
 
 	log "github.com/Sirupsen/logrus"
+	"github.com/gorilla/mux"
 )
 
 func httpGetAltaList(w http.ResponseWriter, r *http.Request, vars map[string]string) (interface{}, error) {
@@ -39,3 +46,246 @@ func httpPostAltaCreate(w http.ResponseWriter, r *http.Request, vars map[string]
 
 	return altaConfig, nil
 }
+
+// Delete an alta container
+func httpRemoveAlta(w http.ResponseWriter, r *http.Request, vars map[string]string) (interface{}, error) {
+	altaId := vars["altaId"]
+
+	// Delete the alta container
+	err := altaCtrler.DeleteAlta(altaId)
+	if err != nil {
+		log.Errorf("Error deleting alta container %s, Err: %v", altaId, err)
+		return nil, err
+	}
+
+	// Create response
+	deleteResp := altaspec.ReqSuccess{
+		Success: true,
+	}
+
+	return deleteResp, nil
+}
+
+// Stop an alta container
+func httpPostAltaStop(w http.ResponseWriter, r *http.Request, vars map[string]string) (interface{}, error) {
+	return altaUserEvent(vars["altaId"], "userStop")
+}
+
+// Start a stopped alta container
+func httpPostAltaStart(w http.ResponseWriter, r *http.Request, vars map[string]string) (interface{}, error) {
+	return altaUserEvent(vars["altaId"], "userStart")
+}
+
+// Restart an alta container
+func httpPostAltaRestart(w http.ResponseWriter, r *http.Request, vars map[string]string) (interface{}, error) {
+	return altaUserEvent(vars["altaId"], "userRestart")
+}
+
+// Post a lifecycle event to an alta container
+func altaUserEvent(altaId string, event string) (interface{}, error) {
+	err := altaCtrler.AltaUserEvent(altaId, event)
+	if err != nil {
+		log.Errorf("Error posting %s event to alta %s, Err: %v", event, altaId, err)
+		return nil, err
+	}
+
+	// Create response
+	eventResp := altaspec.ReqSuccess{
+		Success: true,
+	}
+
+	return eventResp, nil
+}
+
+// Get the orphan container GC audit log
+func httpGetGcAudit(w http.ResponseWriter, r *http.Request, vars map[string]string) (interface{}, error) {
+	return altaCtrler.GcAuditLog(), nil
+}
+
+// Stream logs of an alta container from the node its running on
+func httpGetAltaLogs(w http.ResponseWriter, r *http.Request) {
+	altaId := mux.Vars(r)["altaId"]
+
+	// Build node url with same query parameters
+	url, ok := altaNodeUrl(w, altaId, "/alta/"+altaId+"/logs")
+	if !ok {
+		return
+	}
+	if r.URL.RawQuery != "" {
+		url = url + "?" + r.URL.RawQuery
+	}
+
+	log.Infof("Proxying alta logs from %s", url)
+
+	// Make the request to the node
+	resp, err := http.Get(url)
+	if err != nil {
+		log.Errorf("Error getting logs from node. Err: %v", err)
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close()
+
+	// Copy the response back to the client as it arrives
+	w.Header().Set("Content-Type", resp.Header.Get("Content-Type"))
+	w.WriteHeader(resp.StatusCode)
+	flusher, _ := w.(http.Flusher)
+	buf := make([]byte, 4096)
+	for {
+		n, err := resp.Body.Read(buf)
+		if n > 0 {
+			_, werr := w.Write(buf[:n])
+			if werr != nil {
+				return
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// Find the node url for an alta. Writes http error if not found
+func altaNodeUrl(w http.ResponseWriter, altaId string, path string) (string, bool) {
+	// Find the node where alta is running
+	alta, err := altaCtrler.GetAlta(altaId)
+	if err != nil {
+		http.Error(w, err.Error(), httpErrorCode(err))
+		return "", false
+	}
+	if alta.CurrNode == "" {
+		http.Error(w, "Alta is not scheduled on any node", http.StatusConflict)
+		return "", false
+	}
+
+	// Build node url
+	nodeUrl, err := nodeCtrler.NodeUrl(alta.CurrNode, path)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return "", false
+	}
+
+	return nodeUrl, true
+}
+
+// Execute a command in an alta container on the node its running on
+func httpPostAltaExec(w http.ResponseWriter, r *http.Request) {
+	altaId := mux.Vars(r)["altaId"]
+	var execReq altaspec.AltaExecReq
+
+	// Read the request so that we can forward it
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	err = json.Unmarshal(body, &execReq)
+	if err != nil || len(execReq.Command) == 0 {
+		log.Errorf("Error decoding exec request. Err %v", err)
+		http.Error(w, "Invalid exec request", http.StatusBadRequest)
+		return
+	}
+
+	nodeUrl, ok := altaNodeUrl(w, altaId, "/alta/"+altaId+"/exec")
+	if !ok {
+		return
+	}
+
+	// Interactive sessions need to proxy the raw connection
+	if execReq.Interactive {
+		proxyUpgradedConn(w, r, nodeUrl, body)
+		return
+	}
+
+	log.Infof("Proxying alta exec to %s", nodeUrl)
+
+	// Forward one-shot request to the node
+	resp, err := http.Post(nodeUrl, "application/json", bytes.NewReader(body))
+	if err != nil {
+		log.Errorf("Error performing exec on node. Err: %v", err)
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close()
+
+	// Copy the response back
+	w.Header().Set("Content-Type", resp.Header.Get("Content-Type"))
+	w.WriteHeader(resp.StatusCode)
+	io.Copy(w, resp.Body)
+}
+
+// Proxy an upgraded connection between the client and the node
+func proxyUpgradedConn(w http.ResponseWriter, r *http.Request, nodeUrl string, body []byte) {
+	hijacker, ok := w.(http.Hijacker)
+	if !ok || (r.Header.Get("Upgrade") == "") {
+		http.Error(w, "Interactive exec requires connection upgrade", http.StatusBadRequest)
+		return
+	}
+
+	// Build the request to the node
+	nodeReq, err := http.NewRequest("POST", nodeUrl, bytes.NewReader(body))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	nodeReq.Header.Set("Content-Type", "application/json")
+	nodeReq.Header.Set("Connection", "Upgrade")
+	nodeReq.Header.Set("Upgrade", "tcp")
+
+	// Connect to the node
+	nodeConn, err := net.Dial("tcp", nodeReq.URL.Host)
+	if err != nil {
+		log.Errorf("Error connecting to node %s. Err: %v", nodeReq.URL.Host, err)
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer nodeConn.Close()
+
+	// Send the request and wait for the node to upgrade the connection
+	err = nodeReq.Write(nodeConn)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	nodeReader := bufio.NewReader(nodeConn)
+	nodeResp, err := http.ReadResponse(nodeReader, nodeReq)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	if nodeResp.StatusCode != http.StatusSwitchingProtocols {
+		// Pass the error back to the client
+		defer nodeResp.Body.Close()
+		w.WriteHeader(nodeResp.StatusCode)
+		io.Copy(w, nodeResp.Body)
+		return
+	}
+
+	// Take over the client connection
+	clientConn, clientBuf, err := hijacker.Hijack()
+	if err != nil {
+		log.Errorf("Error hijacking connection. Err: %v", err)
+		return
+	}
+	defer clientConn.Close()
+
+	clientBuf.WriteString("HTTP/1.1 101 UPGRADED\r\n")
+	clientBuf.WriteString("Content-Type: application/vnd.docker.raw-stream\r\n")
+	clientBuf.WriteString("Connection: Upgrade\r\nUpgrade: tcp\r\n\r\n")
+	clientBuf.Flush()
+
+	// Copy data in both directions till one side closes
+	doneChan := make(chan struct{}, 2)
+	go func() {
+		io.Copy(nodeConn, clientBuf.Reader)
+		doneChan <- struct{}{}
+	}()
+	go func() {
+		io.Copy(clientConn, nodeReader)
+		doneChan <- struct{}{}
+	}()
+	<-doneChan
+}
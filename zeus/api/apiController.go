@@ -18,11 +18,15 @@ package api
 import (
 	"errors"
 	"fmt"
+	"net"
+	"strings"
 
 	"github.com/contiv/objmodel/contivModel"
 	"github.com/contiv/objmodel/objdb/modeldb"
 	"github.com/contiv/symphony/pkg/altaspec"
+	"github.com/contiv/symphony/pkg/ipam"
 	"github.com/contiv/symphony/zeus/netCtrler"
+	"github.com/contiv/symphony/zeus/nodeCtrler"
 
 	log "github.com/Sirupsen/logrus"
 	"github.com/gorilla/mux"
@@ -135,8 +139,13 @@ func (self *ApiController) NetworkCreate(network *contivModel.Network) error {
 		return err
 	}
 
-	// Create the network
-	_, err = netCtrler.NewNetwork(network.NetworkName)
+	// Create the network with its subnets and gateways
+	ipv4Spec, ipv6Spec, err := networkIpamSpecs(network)
+	if err != nil {
+		log.Errorf("Invalid subnet for network: %s. Err: %v", network.NetworkName, err)
+		return err
+	}
+	_, err = netCtrler.NewNetwork(network.NetworkName, network.Encap, ipv4Spec, ipv6Spec)
 	if err != nil {
 		log.Errorf("Error creating network: %s. Err: %v", network.NetworkName, err)
 		return err
@@ -145,6 +154,49 @@ func (self *ApiController) NetworkCreate(network *contivModel.Network) error {
 	return nil
 }
 
+// Build address management config of a network. Dual stack networks list
+// an IPv4 subnet and an IPv6 prefix separated by comma, and so do gateways.
+// IPv6 addresses are derived from mac address on /64 prefixes
+func networkIpamSpecs(network *contivModel.Network) (ipam.PoolSpec, ipam.PoolSpec, error) {
+	var ipv4Spec, ipv6Spec ipam.PoolSpec
+
+	for _, subnet := range strings.Split(network.Subnet, ",") {
+		subnet = strings.TrimSpace(subnet)
+		if subnet == "" {
+			continue
+		}
+		_, ipNet, err := net.ParseCIDR(subnet)
+		if err != nil {
+			return ipv4Spec, ipv6Spec, err
+		}
+		if ipNet.IP.To4() != nil {
+			ipv4Spec.Subnet = subnet
+		} else {
+			ones, _ := ipNet.Mask.Size()
+			ipv6Spec.Subnet = subnet
+			ipv6Spec.Slaac = (ones == 64)
+		}
+	}
+
+	for _, gateway := range strings.Split(network.DefaultGw, ",") {
+		gateway = strings.TrimSpace(gateway)
+		if gateway == "" {
+			continue
+		}
+		gwIP := net.ParseIP(gateway)
+		if gwIP == nil {
+			return ipv4Spec, ipv6Spec, errors.New("Invalid gateway " + gateway)
+		}
+		if gwIP.To4() != nil {
+			ipv4Spec.Gateway = gateway
+		} else {
+			ipv6Spec.Gateway = gateway
+		}
+	}
+
+	return ipv4Spec, ipv6Spec, nil
+}
+
 func (self *ApiController) NetworkUpdate(network, params *contivModel.Network) error {
 	log.Infof("Received NetworkUpdate: %+v, params: %+v", network, params)
 	return nil
@@ -152,6 +204,29 @@ func (self *ApiController) NetworkUpdate(network, params *contivModel.Network) e
 
 func (self *ApiController) NetworkDelete(network *contivModel.Network) error {
 	log.Infof("Received NetworkDelete: %+v", network)
+
+	// Release network resources. This fails if network is still in use
+	err := netCtrler.DeleteNetwork(network.NetworkName)
+	if err != nil {
+		log.Errorf("Error deleting network: %s. Err: %v", network.NetworkName, err)
+		return err
+	}
+
+	// Remove it from all nodes
+	nodeCtrler.NetworkDeleteBcast(network.NetworkName)
+
+	// Remove the link from tenant
+	tenant := contivModel.FindTenant(network.TenantName)
+	if tenant != nil {
+		modeldb.RemoveLinkSet(&tenant.LinkSets.Networks, network)
+
+		err = tenant.Write()
+		if err != nil {
+			log.Errorf("Error updating tenant state(%+v). Err: %v", tenant, err)
+			return err
+		}
+	}
+
 	return nil
 }
 func (self *ApiController) PolicyCreate(policy *contivModel.Policy) error {
@@ -335,6 +410,10 @@ func (self *ApiController) ServiceUpdate(service, params *contivModel.Service) e
 
 func (self *ApiController) ServiceDelete(service *contivModel.Service) error {
 	log.Infof("Received ServiceDelete: %+v", service)
+
+	// Remove its scheduling options
+	delServicePolicy(service.TenantName + ":" + service.AppName + ":" + service.ServiceName)
+
 	return nil
 }
 
@@ -379,8 +458,29 @@ func (self *ApiController) ServiceInstanceCreate(serviceInstance *contivModel.Se
 	}
 
 	// container params
+	altaConfig := serviceAltaConfig(service, inst.InstanceID, volumes)
+
+	// Create the container instance
+	err := altaCtrler.CreateAlta(&altaConfig)
+	if err != nil {
+		log.Errorf("Error creating alta container(%+v), Err: %v", altaConfig, err)
+		return err
+	}
+
+	return nil
+}
+
+// Build alta config for an instance of a service
+func serviceAltaConfig(service *contivModel.Service, instId string, volumes []altaspec.AltaVolumeBind) altaspec.AltaConfig {
+	// Labels identifying the service
+	svcLabels := map[string]string{
+		altaspec.TenantLabel:  service.TenantName,
+		altaspec.AppLabel:     service.AppName,
+		altaspec.ServiceLabel: service.ServiceName,
+	}
+
 	altaConfig := altaspec.AltaConfig{
-		Name:        inst.AppName + "." + inst.ServiceName + "." + inst.InstanceID,
+		Name:        service.AppName + "." + service.ServiceName + "." + instId,
 		Image:       service.ImageName,
 		Cpu:         service.Cpu,
 		Memory:      service.Memory,
@@ -388,16 +488,20 @@ func (self *ApiController) ServiceInstanceCreate(serviceInstance *contivModel.Se
 		Networks:    service.Networks,
 		Environment: service.Environment,
 		Volumes:     volumes,
+		Labels:      svcLabels,
+
+		// Dont place two instances of the service on same node
+		AntiAffinity: []altaspec.AltaAffinity{{Selector: svcLabels, Hard: true}},
 	}
 
-	// Create the container instance
-	err := altaCtrler.CreateAlta(&altaConfig)
-	if err != nil {
-		log.Errorf("Error creating alta container(%+v), Err: %v", altaConfig, err)
-		return err
+	// Place all instances or none if the service asked for it
+	serviceKey := service.TenantName + ":" + service.AppName + ":" + service.ServiceName
+	if findServicePolicy(serviceKey).GangSchedule && (service.Scale > 1) {
+		altaConfig.Gang = serviceKey
+		altaConfig.GangSize = int(service.Scale)
 	}
 
-	return nil
+	return altaConfig
 }
 
 func (self *ApiController) ServiceInstanceUpdate(serviceInstance, params *contivModel.ServiceInstance) error {
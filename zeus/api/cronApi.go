@@ -0,0 +1,95 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/contiv/symphony/pkg/altaspec"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// Get a list of cron jobs
+func httpGetCronJobList(w http.ResponseWriter, r *http.Request, vars map[string]string) (interface{}, error) {
+	return cronCtrler.ListCronJobs(), nil
+}
+
+// Create a cron job
+func httpPostCronJobCreate(w http.ResponseWriter, r *http.Request, vars map[string]string) (interface{}, error) {
+	var cronConfig altaspec.CronJobConfig
+
+	// Get cron job config from the request
+	decoder := json.NewDecoder(r.Body)
+	err := decoder.Decode(&cronConfig)
+	if err != nil {
+		log.Errorf("Error decoding cron job create request. Err %v", err)
+		return nil, err
+	}
+
+	// Create the cron job
+	err = cronCtrler.CreateCronJob(&cronConfig)
+	if err != nil {
+		log.Errorf("Error creating cron job(%+v), Err: %v", cronConfig, err)
+		return nil, err
+	}
+
+	return cronConfig, nil
+}
+
+// Suspend a cron job
+func httpPostCronJobSuspend(w http.ResponseWriter, r *http.Request, vars map[string]string) (interface{}, error) {
+	return cronJobSuspend(vars["cronName"], true)
+}
+
+// Resume a suspended cron job
+func httpPostCronJobResume(w http.ResponseWriter, r *http.Request, vars map[string]string) (interface{}, error) {
+	return cronJobSuspend(vars["cronName"], false)
+}
+
+// Suspend or resume a cron job
+func cronJobSuspend(cronName string, suspend bool) (interface{}, error) {
+	err := cronCtrler.SuspendCronJob(cronName, suspend)
+	if err != nil {
+		log.Errorf("Error suspending cron job %s, Err: %v", cronName, err)
+		return nil, err
+	}
+
+	// Create response
+	suspendResp := altaspec.ReqSuccess{
+		Success: true,
+	}
+
+	return suspendResp, nil
+}
+
+// Run a cron job right away
+func httpPostCronJobTrigger(w http.ResponseWriter, r *http.Request, vars map[string]string) (interface{}, error) {
+	cronName := vars["cronName"]
+
+	run, err := cronCtrler.TriggerCronJob(cronName)
+	if err != nil {
+		log.Errorf("Error triggering cron job %s, Err: %v", cronName, err)
+		return nil, err
+	}
+
+	return run, nil
+}
+
+// Delete a cron job and all its jobs
+func httpRemoveCronJob(w http.ResponseWriter, r *http.Request, vars map[string]string) (interface{}, error) {
+	cronName := vars["cronName"]
+
+	// Delete the cron job
+	err := cronCtrler.DeleteCronJob(cronName)
+	if err != nil {
+		log.Errorf("Error deleting cron job %s, Err: %v", cronName, err)
+		return nil, err
+	}
+
+	// Create response
+	deleteResp := altaspec.ReqSuccess{
+		Success: true,
+	}
+
+	return deleteResp, nil
+}
@@ -0,0 +1,57 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/contiv/symphony/pkg/altaspec"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// Get a list of jobs
+func httpGetJobList(w http.ResponseWriter, r *http.Request, vars map[string]string) (interface{}, error) {
+	return altaCtrler.ListJobs(), nil
+}
+
+// Create a run to completion job
+func httpPostJobCreate(w http.ResponseWriter, r *http.Request, vars map[string]string) (interface{}, error) {
+	var altaConfig altaspec.AltaConfig
+
+	// Get job config from the request
+	decoder := json.NewDecoder(r.Body)
+	err := decoder.Decode(&altaConfig)
+	if err != nil {
+		log.Errorf("Error decoding job create request. Err %v", err)
+		return nil, err
+	}
+
+	// Create the job
+	altaConfig.Kind = "job"
+	err = altaCtrler.CreateAlta(&altaConfig)
+	if err != nil {
+		log.Errorf("Error creating job(%+v), Err: %v", altaConfig, err)
+		return nil, err
+	}
+
+	return altaConfig, nil
+}
+
+// Delete a job and all its altas
+func httpRemoveJob(w http.ResponseWriter, r *http.Request, vars map[string]string) (interface{}, error) {
+	jobName := vars["jobName"]
+
+	// Delete the job
+	err := altaCtrler.DeleteJob(jobName)
+	if err != nil {
+		log.Errorf("Error deleting job %s, Err: %v", jobName, err)
+		return nil, err
+	}
+
+	// Create response
+	deleteResp := altaspec.ReqSuccess{
+		Success: true,
+	}
+
+	return deleteResp, nil
+}
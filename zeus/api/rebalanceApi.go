@@ -0,0 +1,10 @@
+package api
+
+import (
+	"net/http"
+)
+
+// Report the moves rebalancer would make right now without making them
+func httpGetRebalanceReport(w http.ResponseWriter, r *http.Request, vars map[string]string) (interface{}, error) {
+	return rebalanceCtrler.RebalanceReport(), nil
+}
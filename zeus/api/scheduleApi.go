@@ -0,0 +1,66 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/contiv/objmodel/contivModel"
+	"github.com/contiv/symphony/pkg/altaspec"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// Scheduling simulation request. Either an alta or a service
+type simulateReq struct {
+	Alta    *altaspec.AltaConfig `json:"alta"`    // Alta to simulate
+	Service *contivModel.Service `json:"service"` // Service to simulate
+	Scale   int                  `json:"scale"`   // Number of instances
+}
+
+// Max number of instances in a simulation
+const maxSimulateScale = 1000
+
+// Simulate where altas would be placed without creating them
+func httpPostScheduleSimulate(w http.ResponseWriter, r *http.Request, vars map[string]string) (interface{}, error) {
+	var simReq simulateReq
+
+	// Get simulation request
+	decoder := json.NewDecoder(r.Body)
+	err := decoder.Decode(&simReq)
+	if err != nil {
+		log.Errorf("Error decoding simulate request. Err %v", err)
+		return nil, err
+	}
+
+	// Determine the scale
+	scale := simReq.Scale
+	if (scale == 0) && (simReq.Service != nil) {
+		scale = int(simReq.Service.Scale)
+	}
+	if scale == 0 {
+		scale = 1
+	}
+	if (scale < 0) || (scale > maxSimulateScale) {
+		return nil, fmt.Errorf("Scale must be between 1 and %d", maxSimulateScale)
+	}
+
+	// Build the alta configs for each instance
+	var altaConfigs []altaspec.AltaConfig
+	for idx := 0; idx < scale; idx++ {
+		if simReq.Alta != nil {
+			altaConfig := *simReq.Alta
+			if (scale > 1) && (altaConfig.Name != "") {
+				altaConfig.Name = fmt.Sprintf("%s-%d", altaConfig.Name, idx+1)
+			}
+			altaConfigs = append(altaConfigs, altaConfig)
+		} else if simReq.Service != nil {
+			altaConfigs = append(altaConfigs, serviceAltaConfig(simReq.Service, fmt.Sprintf("%d", idx+1), nil))
+		} else {
+			return nil, errors.New("Simulate request needs an alta or a service")
+		}
+	}
+
+	return altaCtrler.SimulateAltas(altaConfigs)
+}
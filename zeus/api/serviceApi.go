@@ -0,0 +1,97 @@
+package api
+
+// This file implements scheduling options for services that are not part of
+// the contiv model. Options are read only when service instances are created,
+// so they must be set before the service is created. Changing them later does
+// not affect existing instances
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/contiv/objmodel/contivModel"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// Scheduling options for a service
+type ServicePolicy struct {
+	GangSchedule bool `json:"gangSchedule"` // Place all instances of the service or none of them
+}
+
+// Key for a service policy in conf store
+func servicePolicyKey(serviceKey string) string {
+	return "servicePolicy/" + serviceKey
+}
+
+// Find the policy of a service. Returns default policy if none was set
+func findServicePolicy(serviceKey string) ServicePolicy {
+	var policy ServicePolicy
+
+	if zeusCdb != nil {
+		err := zeusCdb.GetObj(servicePolicyKey(serviceKey), &policy)
+		if err != nil {
+			log.Debugf("No policy for service %s. Using defaults", serviceKey)
+		}
+	}
+
+	return policy
+}
+
+// Delete the policy of a service
+func delServicePolicy(serviceKey string) {
+	// Nothing was saved without a conf store
+	if zeusCdb == nil {
+		return
+	}
+
+	err := zeusCdb.DelObj(servicePolicyKey(serviceKey))
+	if err != nil {
+		log.Debugf("Error deleting policy of service %s. Err: %v", serviceKey, err)
+	}
+}
+
+// Get the scheduling policy of a service
+func httpGetServicePolicy(w http.ResponseWriter, r *http.Request, vars map[string]string) (interface{}, error) {
+	serviceKey := vars["tenantName"] + ":" + vars["appName"] + ":" + vars["serviceName"]
+
+	return findServicePolicy(serviceKey), nil
+}
+
+// Set the scheduling policy of a service. Applies only to instances created
+// after this, so set it before creating the service
+func httpPostServicePolicy(w http.ResponseWriter, r *http.Request, vars map[string]string) (interface{}, error) {
+	var policy ServicePolicy
+	serviceKey := vars["tenantName"] + ":" + vars["appName"] + ":" + vars["serviceName"]
+
+	// Policy is saved in conf store
+	if zeusCdb == nil {
+		log.Errorf("No conf store to save policy of service %s", serviceKey)
+		return nil, errors.New("Service policy requires a conf store")
+	}
+
+	// Get the policy
+	decoder := json.NewDecoder(r.Body)
+	err := decoder.Decode(&policy)
+	if err != nil {
+		log.Errorf("Error decoding service policy. Err %v", err)
+		return nil, err
+	}
+
+	// Save it to conf store
+	err = zeusCdb.SetObj(servicePolicyKey(serviceKey), policy)
+	if err != nil {
+		log.Errorf("Error saving policy of service %s. Err: %v", serviceKey, err)
+		return nil, errors.New("Error saving service policy")
+	}
+
+	log.Infof("Set policy of service %s to %+v", serviceKey, policy)
+
+	// Existing instances keep the policy they were created with
+	if contivModel.FindService(serviceKey) != nil {
+		log.Warnf("Service %s already exists. Policy applies only to instances created from now", serviceKey)
+	}
+
+	return policy, nil
+}
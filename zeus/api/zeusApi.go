@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"strconv"
 
+	"github.com/contiv/objmodel/objdb"
 	"github.com/contiv/symphony/zeus/common"
 
 	log "github.com/Sirupsen/logrus"
@@ -14,13 +15,20 @@ import (
 type HttpApiFunc func(w http.ResponseWriter, r *http.Request, vars map[string]string) (interface{}, error)
 
 var altaCtrler common.AltaCtrlInterface
+var cronCtrler common.CronCtrlInterface
+var rebalanceCtrler common.RebalanceCtrlInterface
 var apiCtrler *ApiController
+var zeusCdb objdb.ObjdbApi
 
 // Create a HTTP Server and initialize the router
-func CreateServer(port int, ctrlers *common.ZeusCtrlers) {
+func CreateServer(port int, ctrlers *common.ZeusCtrlers, cdb objdb.ObjdbApi) {
 	listenAddr := ":" + strconv.Itoa(port)
 
+	zeusCdb = cdb
+
 	altaCtrler = ctrlers.AltaCtrler
+	cronCtrler = ctrlers.CronCtrler
+	rebalanceCtrler = ctrlers.Rebalancer
 
 	// Create a router
 	router := createRouter()
@@ -50,14 +58,37 @@ func createRouter() *mux.Router {
 	// List of routes
 	routeMap := map[string]map[string]HttpApiFunc{
 		"GET": {
-			"/node/": httpGetNodeList,
-			"/alta/": httpGetAltaList,
+			"/node/":    httpGetNodeList,
+			"/alta/":    httpGetAltaList,
+			"/audit/gc": httpGetGcAudit,
+			"/job/":     httpGetJobList,
+			"/cronjob/": httpGetCronJobList,
+
+			"/rebalance/report": httpGetRebalanceReport,
+
+			"/service/{tenantName}/{appName}/{serviceName}/policy": httpGetServicePolicy,
 		},
 		"POST": {
-			"/alta/create": httpPostAltaCreate,
+			"/alta/create":           httpPostAltaCreate,
+			"/job/create":            httpPostJobCreate,
+			"/alta/{altaId}/stop":    httpPostAltaStop,
+			"/alta/{altaId}/start":   httpPostAltaStart,
+			"/alta/{altaId}/restart": httpPostAltaRestart,
+			"/cronjob/create":        httpPostCronJobCreate,
+			"/schedule/simulate":     httpPostScheduleSimulate,
+
+			"/cronjob/{cronName}/suspend": httpPostCronJobSuspend,
+			"/cronjob/{cronName}/resume":  httpPostCronJobResume,
+			"/cronjob/{cronName}/trigger": httpPostCronJobTrigger,
+
+			// Set before creating the service. Existing instances are not changed
+			"/service/{tenantName}/{appName}/{serviceName}/policy": httpPostServicePolicy,
 		},
 		"DELETE": {
-		// "/alta/{altaId}":        httpRemoveAlta,
+			"/alta/{altaId}": httpRemoveAlta,
+			"/job/{jobName}": httpRemoveJob,
+
+			"/cronjob/{cronName}": httpRemoveCronJob,
 		},
 	}
 
@@ -79,6 +110,24 @@ func createRouter() *mux.Router {
 		}
 	}
 
+	// Routes that stream their response instead of returning json
+	streamRouteMap := map[string]map[string]http.HandlerFunc{
+		"GET": {
+			"/alta/{altaId}/logs": httpGetAltaLogs,
+		},
+		"POST": {
+			"/alta/{altaId}/exec": httpPostAltaExec,
+		},
+	}
+
+	// Register each streaming method/path
+	for method, routes := range streamRouteMap {
+		for route, funct := range routes {
+			log.Infof("Registering %s %s", method, route)
+			router.Path(route).Methods(method).HandlerFunc(funct)
+		}
+	}
+
 	return router
 }
 
@@ -97,7 +146,7 @@ func makeHttpHandler(localMethod string, localRoute string, handlerFunc HttpApiF
 			log.Errorf("Handler for %s %s returned error: %s", localMethod, localRoute, err)
 
 			// Send HTTP response
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			http.Error(w, err.Error(), httpErrorCode(err))
 		} else {
 			respJson, _ := json.Marshal(resp)
 			if localMethod == "GET" {
@@ -112,6 +161,23 @@ func makeHttpHandler(localMethod string, localRoute string, handlerFunc HttpApiF
 	}
 }
 
+// Map controller errors to HTTP status codes
+func httpErrorCode(err error) int {
+	// Invalid config is a bad request
+	if _, ok := err.(*common.ConfigError); ok {
+		return http.StatusBadRequest
+	}
+
+	switch err {
+	case common.ErrAltaNotFound, common.ErrJobNotFound, common.ErrCronJobNotFound:
+		return http.StatusNotFound
+	case common.ErrInvalidEvent:
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
 // writeJSON: writes the value v to the http response stream as json with standard
 // json encoding.
 func writeJSON(w http.ResponseWriter, code int, v interface{}) error {
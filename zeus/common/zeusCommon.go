@@ -17,15 +17,129 @@ package common
 // Common defenitions to be used across zeus modules
 
 import (
+	"errors"
+	"time"
+
 	"github.com/contiv/symphony/pkg/altaspec"
 )
 
+// Errors returned by the controllers
+var (
+	ErrAltaNotFound = errors.New("Alta not found")
+	ErrInvalidEvent = errors.New("Operation not allowed in current state")
+	ErrJobNotFound  = errors.New("Job not found")
+
+	ErrCronJobNotFound = errors.New("Cron job not found")
+)
+
+// Error in the config given by the user. Request is rejected without creating anything
+type ConfigError struct {
+	Err error // What was wrong with the config
+}
+
+func (self *ConfigError) Error() string {
+	return self.Err.Error()
+}
+
 type AltaCtrlInterface interface {
 	CreateAlta(altaConfig *altaspec.AltaConfig) error
+	// Waits for the alta to tear down. Fails if the alta was already deleted
+	DeleteAlta(altaId string) error
+
+	// Waits for the alta to process the event. Fails if its not allowed in current state
+	AltaUserEvent(altaId string, event string) error
+	MigrateAlta(altaId string, toNode string) error
+
+	// Alta state is a copy. Safe to use from any goroutine
+	GetAlta(altaId string) (*AltaState, error)
 	RestoreAltaActors() error
 	ListAlta() []*AltaState
 	ReconcileNode(nodeAddr string, altaList []altaspec.AltaContext) error
 	NodeDownEvent(nodeAddr string) error
+	GcAuditLog() []GcAuditEntry
+	CreateJob(altaConfig *altaspec.AltaConfig) error
+	DeleteJob(jobName string) error
+	ListJobs() []*JobState
+	SimulateAltas(altaConfigs []altaspec.AltaConfig) ([]*SimulateResult, error)
+}
+
+type CronCtrlInterface interface {
+	CreateCronJob(cronConfig *altaspec.CronJobConfig) error
+	DeleteCronJob(name string) error
+	SuspendCronJob(name string, suspend bool) error
+	TriggerCronJob(name string) (*CronRun, error)
+	ListCronJobs() []*CronJobState
+	RestoreCronJobs() error
+	Stop()
+}
+
+type RebalanceCtrlInterface interface {
+	RebalanceReport() *RebalanceReport
+	Stop()
+}
+
+// Moves rebalancer would make to even out node utilization
+type RebalanceReport struct {
+	Time      time.Time          // When the report was generated
+	Imbalance float64            // Difference between busiest and idlest node utilization
+	Threshold float64            // Imbalance that triggers rebalancing
+	NodeUsage map[string]float64 // Utilization of each node from 0 to 1, before the moves
+	Moves     []RebalanceMove    // Altas to move
+	Skipped   []RebalanceSkip    // Altas on busy nodes that cant be moved
+}
+
+// Alta to move
+type RebalanceMove struct {
+	AltaId   string // Alta to move
+	AltaName string // Name of the alta
+	FromNode string // Node alta is running on
+	ToNode   string // Node scheduler would move it to
+}
+
+// Alta rebalancer cant move
+type RebalanceSkip struct {
+	AltaId   string // Alta that was skipped
+	AltaName string // Name of the alta
+	NodeAddr string // Node alta is running on
+	Reason   string // Why it cant be moved
+}
+
+// A single run of a cron job
+type CronRun struct {
+	JobName       string    // Name of the job created for this run
+	ScheduledTime time.Time // When the run was scheduled
+	Trigger       string    // schedule or manual
+	Result        string    // active, complete, failed, replaced, skipped or deleted
+}
+
+// State of a cron job
+type CronJobState struct {
+	Config       altaspec.CronJobConfig // Cron job configuration
+	LastSchedule time.Time              // Last time a run was scheduled
+	NextSchedule time.Time              // Next time a run will be scheduled
+	Active       []string               // Jobs that are still running
+	History      []CronRun              // Past runs, oldest first
+}
+
+// State of a run to completion job
+type JobState struct {
+	JobName     string               // Name of the job
+	State       string               // running, complete or failed
+	Job         altaspec.AltaJobSpec // Job parameters
+	Succeeded   int                  // Number of altas that completed successfully
+	Failed      int                  // Number of altas that failed
+	Active      []string             // Alta ids still running
+	CreatedTime time.Time            // When the job was created
+}
+
+// Audit entry for orphan container garbage collection
+type GcAuditEntry struct {
+	Time        time.Time // When the action was taken
+	NodeAddr    string    // Node where container was found
+	ContainerId string    // Container id
+	AltaId      string    // Alta id node reported, if any
+	Action      string    // detected, removed or removeFailed
+	Reason      string    // Why the action was taken
 }
 
 // State of alta container
@@ -34,8 +148,40 @@ type AltaState struct {
 	CurrNode    string            // Node where this container is placed
 	ContainerId string            // ContainerId on current node
 	FsmState    string            // FSM for the container
+
+	RestartCount int    // Number of times container was restarted
+	NumRetries   int    // Number of failed attempts in current state
+	LastError    string // Last error seen by the container
+	Ready        bool   // Readiness probe succeeded
+	ExitCode     int    // Exit code when the container last exited
+
+	PendingReasons []NodeRejection   // Why the alta could not be scheduled
+	Events         []AltaEventRecord // Notable things that happened to the alta, oldest first
+}
+
+// Notable event in the life of an alta
+type AltaEventRecord struct {
+	Time    time.Time // When it happened
+	Reason  string    // Short reason, e.g. Preempted
+	Message string    // Details
+}
+
+// Reason a node was rejected by the scheduler
+type NodeRejection struct {
+	NodeAddr string // Node that was rejected. Empty if reason applies to all nodes
+	Reason   string // Filter or resource that rejected the node
+}
+
+// Result of simulating the placement of an alta
+type SimulateResult struct {
+	AltaName   string          // Name of the alta
+	NodeAddr   string          // Node alta would be placed on. Empty if it cant be placed
+	Error      string          // Why the alta cant be placed
+	Rejections []NodeRejection // Why each node was rejected
 }
 
 type ZeusCtrlers struct {
 	AltaCtrler AltaCtrlInterface
+	CronCtrler CronCtrlInterface
+	Rebalancer RebalanceCtrlInterface
 }
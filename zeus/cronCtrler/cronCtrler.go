@@ -0,0 +1,451 @@
+package cronCtrler
+
+// This file implements cron jobs. Each cron job creates a run to completion
+// job from its template whenever its schedule fires
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/contiv/symphony/pkg/altaspec"
+	"github.com/contiv/symphony/zeus/common"
+
+	"github.com/contiv/objmodel/objdb"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+const (
+	cronTickInterval    = time.Second * 10 // How often schedules are checked
+	cronMissedDeadline  = time.Minute * 10 // Runs missed by more than this are skipped
+	defaultHistoryLimit = 10               // Default number of past runs to keep
+	maxHistoryLimit     = 100              // Max number of past runs to keep
+)
+
+// Cron job state to be persisted
+type CronJobModel struct {
+	Config       altaspec.CronJobConfig // Cron job configuration
+	LastSchedule time.Time              // Last time a run was scheduled
+	NextSchedule time.Time              // Next time a run will be scheduled
+	Active       []string               // Jobs that are still running
+	History      []common.CronRun       // Past runs, oldest first
+
+	NumRuns int // Number of runs started so far. Keeps run names unique
+}
+
+// State of a cron job
+type CronJob struct {
+	Model    CronJobModel  // Model for the cron job
+	schedule *CronSchedule // Parsed schedule
+}
+
+// Cron controller
+type CronMgr struct {
+	cdb        objdb.ObjdbApi           // Conf store
+	altaCtrler common.AltaCtrlInterface // Alta controller to create jobs
+	mutex      sync.Mutex               // Lock for the cron db
+	cronDb     map[string]*CronJob      // DB of cron jobs
+	stopChan   chan struct{}            // Channel to stop the run loop
+}
+
+// Create a new cron controller
+func NewCronCtrler(cdb objdb.ObjdbApi, altaCtrler common.AltaCtrlInterface) *CronMgr {
+	cronCtrl := new(CronMgr)
+
+	cronCtrl.cdb = cdb
+	cronCtrl.altaCtrler = altaCtrler
+	cronCtrl.cronDb = make(map[string]*CronJob)
+	cronCtrl.stopChan = make(chan struct{})
+
+	// Kick off the run loop
+	go cronCtrl.runLoop()
+
+	return cronCtrl
+}
+
+// Stop scheduling cron jobs. Called when we lose mastership
+func (self *CronMgr) Stop() {
+	close(self.stopChan)
+}
+
+// Periodically check the schedule of all cron jobs
+func (self *CronMgr) runLoop() {
+	ticker := time.NewTicker(cronTickInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			self.syncAllCronJobs()
+		case <-self.stopChan:
+			log.Infof("Stopping cron controller")
+			return
+		}
+	}
+}
+
+// Check all cron jobs
+func (self *CronMgr) syncAllCronJobs() {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	// Get the state of all jobs
+	jobStates := make(map[string]*common.JobState)
+	for _, jobState := range self.altaCtrler.ListJobs() {
+		jobStates[jobState.JobName] = jobState
+	}
+
+	now := time.Now()
+	for _, cron := range self.cronDb {
+		self.syncCronJob(cron, jobStates, now)
+	}
+}
+
+// Update active runs and start a new run if the schedule fired
+func (self *CronMgr) syncCronJob(cron *CronJob, jobStates map[string]*common.JobState, now time.Time) {
+	changed := false
+
+	// Check on the active jobs
+	var active []string
+	for _, jobName := range cron.Model.Active {
+		result := "active"
+		jobState := jobStates[jobName]
+		if jobState == nil {
+			result = "deleted"
+		} else if (jobState.State == "complete") || (jobState.State == "failed") {
+			result = jobState.State
+		}
+
+		if result == "active" {
+			active = append(active, jobName)
+			continue
+		}
+
+		log.Infof("Job %s of cron job %s finished. Result: %s", jobName, cron.Model.Config.Name, result)
+		changed = true
+
+		// Delete finished jobs that are no longer in the history
+		if !cron.setRunResult(jobName, result) && (jobState != nil) {
+			self.altaCtrler.DeleteJob(jobName)
+		}
+	}
+	cron.Model.Active = active
+
+	// Check if the schedule fired
+	if !cron.Model.Config.Suspend && !cron.Model.NextSchedule.IsZero() &&
+		!now.Before(cron.Model.NextSchedule) {
+		scheduledTime := cron.Model.NextSchedule
+		cron.Model.NextSchedule = cron.schedule.Next(now)
+		changed = true
+
+		// Skip runs that were missed for too long, e.g. while there was no master
+		if now.Sub(scheduledTime) > cronMissedDeadline {
+			log.Warnf("Cron job %s missed its schedule at %v. Skipping", cron.Model.Config.Name, scheduledTime)
+		} else {
+			self.startRun(cron, scheduledTime, "schedule")
+		}
+	}
+
+	if changed {
+		self.saveModel(cron)
+	}
+}
+
+// Start a new run of the cron job based on its concurrency policy
+func (self *CronMgr) startRun(cron *CronJob, scheduledTime time.Time, trigger string) (*common.CronRun, error) {
+	// Sequence number keeps runs triggered in the same second apart
+	cron.Model.NumRuns++
+	run := common.CronRun{
+		JobName:       fmt.Sprintf("%s-%d-%d", cron.Model.Config.Name, scheduledTime.Unix(), cron.Model.NumRuns),
+		ScheduledTime: scheduledTime,
+		Trigger:       trigger,
+	}
+
+	// Apply concurrency policy if previous runs are still active
+	if len(cron.Model.Active) > 0 {
+		switch cron.Model.Config.ConcurrencyPolicy {
+		case "forbid":
+			log.Infof("Cron job %s has active runs. Skipping run %s", cron.Model.Config.Name, run.JobName)
+			run.Result = "skipped"
+			self.addHistory(cron, run)
+			return nil, common.ErrInvalidEvent
+		case "replace":
+			for _, jobName := range cron.Model.Active {
+				log.Infof("Replacing job %s of cron job %s", jobName, cron.Model.Config.Name)
+
+				err := self.altaCtrler.DeleteJob(jobName)
+				if err != nil {
+					log.Errorf("Error deleting job %s. Err: %v", jobName, err)
+				}
+				cron.setRunResult(jobName, "replaced")
+			}
+			cron.Model.Active = nil
+		}
+	}
+
+	// Create the job from the template
+	jobConfig := cron.Model.Config.JobTemplate
+	jobConfig.Name = run.JobName
+	jobConfig.Kind = "job"
+
+	err := self.altaCtrler.CreateAlta(&jobConfig)
+	if err != nil {
+		log.Errorf("Error creating job %s for cron job %s. Err: %v", run.JobName, cron.Model.Config.Name, err)
+		run.Result = "failed"
+		self.addHistory(cron, run)
+		return nil, err
+	}
+
+	log.Infof("Cron job %s started job %s", cron.Model.Config.Name, run.JobName)
+
+	run.Result = "active"
+	cron.Model.Active = append(cron.Model.Active, run.JobName)
+	cron.Model.LastSchedule = scheduledTime
+	self.addHistory(cron, run)
+
+	return &run, nil
+}
+
+// Add a run to history and trim the history to its limit
+func (self *CronMgr) addHistory(cron *CronJob, run common.CronRun) {
+	cron.Model.History = append(cron.Model.History, run)
+
+	for len(cron.Model.History) > cron.Model.Config.HistoryLimit {
+		oldest := cron.Model.History[0]
+		cron.Model.History = cron.Model.History[1:]
+
+		// Cleanup finished jobs. Active jobs are cleaned up when they finish
+		if (oldest.Result == "complete") || (oldest.Result == "failed") {
+			self.altaCtrler.DeleteJob(oldest.JobName)
+		}
+	}
+}
+
+// Set the result of a run in history. Returns false if the run is not in history
+func (self *CronJob) setRunResult(jobName, result string) bool {
+	for idx := range self.Model.History {
+		if self.Model.History[idx].JobName == jobName {
+			self.Model.History[idx].Result = result
+			return true
+		}
+	}
+
+	return false
+}
+
+// Return externally visible state of the cron job
+func (self *CronJob) cronState() *common.CronJobState {
+	return &common.CronJobState{
+		Config:       self.Model.Config,
+		LastSchedule: self.Model.LastSchedule,
+		NextSchedule: self.Model.NextSchedule,
+		Active:       append([]string{}, self.Model.Active...),
+		History:      append([]common.CronRun{}, self.Model.History...),
+	}
+}
+
+// Save cron job state to conf store
+func (self *CronMgr) saveModel(cron *CronJob) error {
+	storeKey := "cronjob/" + cron.Model.Config.Name
+
+	// Save it to conf store
+	err := self.cdb.SetObj(storeKey, cron.Model)
+	if err != nil {
+		log.Errorf("Error storing object %+v. Err: %v", cron.Model, err)
+		return err
+	}
+
+	return nil
+}
+
+// Create a new cron job
+func (self *CronMgr) CreateCronJob(cronConfig *altaspec.CronJobConfig) error {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	// Validate the config
+	if cronConfig.Name == "" {
+		log.Errorf("Cron job name is required")
+		return errors.New("Cron job name is required")
+	}
+	if self.cronDb[cronConfig.Name] != nil {
+		log.Errorf("Error: Cron job %s already exists", cronConfig.Name)
+		return errors.New("Cron job already exists")
+	}
+	if cronConfig.JobTemplate.Image == "" {
+		log.Errorf("Cron job %s has no image in job template", cronConfig.Name)
+		return errors.New("Job template requires an image")
+	}
+
+	schedule, err := ParseCron(cronConfig.Schedule)
+	if err != nil {
+		log.Errorf("Invalid schedule %s for cron job %s. Err: %v", cronConfig.Schedule, cronConfig.Name, err)
+		return err
+	}
+
+	// Set the defaults
+	config := *cronConfig
+	switch config.ConcurrencyPolicy {
+	case "":
+		config.ConcurrencyPolicy = "allow"
+	case "allow", "forbid", "replace":
+	default:
+		log.Errorf("Invalid concurrency policy %s", config.ConcurrencyPolicy)
+		return errors.New("Invalid concurrency policy")
+	}
+	if config.HistoryLimit <= 0 {
+		config.HistoryLimit = defaultHistoryLimit
+	} else if config.HistoryLimit > maxHistoryLimit {
+		config.HistoryLimit = maxHistoryLimit
+	}
+
+	cron := &CronJob{
+		Model: CronJobModel{
+			Config:       config,
+			NextSchedule: schedule.Next(time.Now()),
+		},
+		schedule: schedule,
+	}
+
+	log.Infof("Creating cron job: %+v", cron.Model)
+
+	self.cronDb[config.Name] = cron
+
+	return self.saveModel(cron)
+}
+
+// Delete a cron job and all jobs it created
+func (self *CronMgr) DeleteCronJob(name string) error {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	cron := self.cronDb[name]
+	if cron == nil {
+		log.Errorf("Cron job %s not found", name)
+		return common.ErrCronJobNotFound
+	}
+
+	// Delete the jobs
+	jobNames := make(map[string]bool)
+	for _, jobName := range cron.Model.Active {
+		jobNames[jobName] = true
+	}
+	for _, run := range cron.Model.History {
+		if (run.Result == "complete") || (run.Result == "failed") {
+			jobNames[run.JobName] = true
+		}
+	}
+	for jobName := range jobNames {
+		err := self.altaCtrler.DeleteJob(jobName)
+		if err != nil {
+			log.Errorf("Error deleting job %s. Err: %v", jobName, err)
+		}
+	}
+
+	// Remove it from conf store
+	storeKey := "cronjob/" + name
+	err := self.cdb.DelObj(storeKey)
+	if err != nil {
+		log.Errorf("Error deleting object %s. Err: %v", storeKey, err)
+	}
+
+	delete(self.cronDb, name)
+
+	return nil
+}
+
+// Suspend or resume a cron job
+func (self *CronMgr) SuspendCronJob(name string, suspend bool) error {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	cron := self.cronDb[name]
+	if cron == nil {
+		log.Errorf("Cron job %s not found", name)
+		return common.ErrCronJobNotFound
+	}
+
+	cron.Model.Config.Suspend = suspend
+
+	// Dont run the schedules missed while suspended
+	if !suspend {
+		cron.Model.NextSchedule = cron.schedule.Next(time.Now())
+	}
+
+	log.Infof("Cron job %s suspend: %v", name, suspend)
+
+	return self.saveModel(cron)
+}
+
+// Run a cron job right away
+func (self *CronMgr) TriggerCronJob(name string) (*common.CronRun, error) {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	cron := self.cronDb[name]
+	if cron == nil {
+		log.Errorf("Cron job %s not found", name)
+		return nil, common.ErrCronJobNotFound
+	}
+
+	run, err := self.startRun(cron, time.Now(), "manual")
+	self.saveModel(cron)
+
+	return run, err
+}
+
+// Return a list of all cron jobs
+func (self *CronMgr) ListCronJobs() []*common.CronJobState {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	cronList := make([]*common.CronJobState, 0)
+
+	for _, cron := range self.cronDb {
+		cronList = append(cronList, cron.cronState())
+	}
+
+	return cronList
+}
+
+// Restore cron job state from cdb
+func (self *CronMgr) RestoreCronJobs() error {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	// Get the list of elements
+	jsonArr, err := self.cdb.ListDir("cronjob")
+	if err != nil {
+		log.Errorf("Error restoring cron job state")
+		return err
+	}
+
+	// Loop thru each cron job model
+	for _, elemStr := range jsonArr {
+		// Parse the json model
+		var model CronJobModel
+		err = json.Unmarshal([]byte(elemStr), &model)
+		if err != nil {
+			log.Errorf("Error parsing object %s, Err %v", elemStr, err)
+			return err
+		}
+
+		schedule, err := ParseCron(model.Config.Schedule)
+		if err != nil {
+			log.Errorf("Invalid schedule for cron job %s. Err: %v", model.Config.Name, err)
+			continue
+		}
+
+		self.cronDb[model.Config.Name] = &CronJob{
+			Model:    model,
+			schedule: schedule,
+		}
+
+		log.Infof("Restored cron job: %+v", model)
+	}
+
+	return nil
+}
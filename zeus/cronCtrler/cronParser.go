@@ -0,0 +1,201 @@
+package cronCtrler
+
+// This file implements parsing standard 5 field cron expressions
+//   minute hour day-of-month month day-of-week
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Parsed cron schedule. Each field is a bitmap of allowed values
+type CronSchedule struct {
+	minute uint64
+	hour   uint64
+	dom    uint64
+	month  uint64
+	dow    uint64
+	domAny bool // day of month was specified as *
+	dowAny bool // day of week was specified as *
+}
+
+// Allowed range for each field
+var cronFieldRange = []struct{ min, max int }{
+	{0, 59}, // minute
+	{0, 23}, // hour
+	{1, 31}, // day of month
+	{1, 12}, // month
+	{0, 7},  // day of week. Both 0 and 7 are sunday
+}
+
+// Well known schedules
+var cronShortcuts = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// Parse a cron expression
+func ParseCron(expr string) (*CronSchedule, error) {
+	expr = strings.TrimSpace(expr)
+	if cronShortcuts[expr] != "" {
+		expr = cronShortcuts[expr]
+	}
+
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, errors.New("Cron expression needs 5 fields")
+	}
+
+	// Parse each field
+	var bits [5]uint64
+	for i, field := range fields {
+		fieldBits, err := parseCronField(field, cronFieldRange[i].min, cronFieldRange[i].max)
+		if err != nil {
+			return nil, err
+		}
+		bits[i] = fieldBits
+	}
+
+	sched := CronSchedule{
+		minute: bits[0],
+		hour:   bits[1],
+		dom:    bits[2],
+		month:  bits[3],
+		dow:    bits[4],
+		domAny: (fields[2] == "*"),
+		dowAny: (fields[4] == "*"),
+	}
+
+	// Treat 7 as sunday
+	if sched.dow&(1<<7) != 0 {
+		sched.dow = (sched.dow | 1) &^ (1 << 7)
+	}
+
+	return &sched, nil
+}
+
+// Parse a single field of the form *, n, a-b, */s, a-b/s or a comma separated list of these
+func parseCronField(field string, min, max int) (uint64, error) {
+	var bits uint64
+
+	for _, part := range strings.Split(field, ",") {
+		rangePart := part
+		step := 1
+
+		// Parse the step
+		if idx := strings.Index(part, "/"); idx >= 0 {
+			rangePart = part[:idx]
+			s, err := strconv.Atoi(part[idx+1:])
+			if err != nil || s <= 0 {
+				return 0, errors.New("Invalid step in cron field " + field)
+			}
+			step = s
+		}
+
+		// Parse the range
+		var lo, hi int
+		var err error
+		if rangePart == "*" {
+			lo, hi = min, max
+		} else if idx := strings.Index(rangePart, "-"); idx >= 0 {
+			lo, err = strconv.Atoi(rangePart[:idx])
+			if err != nil {
+				return 0, errors.New("Invalid cron field " + field)
+			}
+			hi, err = strconv.Atoi(rangePart[idx+1:])
+			if err != nil {
+				return 0, errors.New("Invalid cron field " + field)
+			}
+		} else {
+			lo, err = strconv.Atoi(rangePart)
+			if err != nil {
+				return 0, errors.New("Invalid cron field " + field)
+			}
+			hi = lo
+
+			// n/s means starting at n
+			if step != 1 {
+				hi = max
+			}
+		}
+
+		if (lo < min) || (hi > max) || (lo > hi) {
+			return 0, errors.New("Cron field out of range " + field)
+		}
+
+		for i := lo; i <= hi; i += step {
+			bits |= 1 << uint(i)
+		}
+	}
+
+	return bits, nil
+}
+
+// Check if the day matches day of month and day of week fields.
+// Like standard cron, if both are restricted either one can match
+func (self *CronSchedule) dayMatches(t time.Time) bool {
+	domMatch := (self.dom & (1 << uint(t.Day()))) != 0
+	dowMatch := (self.dow & (1 << uint(t.Weekday()))) != 0
+
+	if self.domAny || self.dowAny {
+		return domMatch && dowMatch
+	}
+
+	return domMatch || dowMatch
+}
+
+// Return the next time after t that matches the schedule.
+// Returns zero time if there is no match in next five years
+func (self *CronSchedule) Next(t time.Time) time.Time {
+	loc := t.Location()
+
+	// Start from the next minute
+	t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, loc).Add(time.Minute)
+	yearLimit := t.Year() + 5
+
+WRAP:
+	if t.Year() > yearLimit {
+		return time.Time{}
+	}
+
+	// Find matching month
+	for (self.month & (1 << uint(t.Month()))) == 0 {
+		t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+		if t.Month() == time.January {
+			goto WRAP
+		}
+	}
+
+	// Find matching day
+	for !self.dayMatches(t) {
+		t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+		if t.Day() == 1 {
+			goto WRAP
+		}
+	}
+
+	// Find matching hour
+	for (self.hour & (1 << uint(t.Hour()))) == 0 {
+		t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+		if t.Hour() == 0 {
+			goto WRAP
+		}
+	}
+
+	// Find matching minute
+	for (self.minute & (1 << uint(t.Minute()))) == 0 {
+		t = t.Add(time.Minute)
+		if t.Minute() == 0 {
+			goto WRAP
+		}
+	}
+
+	return t
+}
@@ -0,0 +1,86 @@
+package cronCtrler
+
+import (
+	"testing"
+	"time"
+)
+
+// Test parsing valid and invalid cron expressions
+func TestParseCron(t *testing.T) {
+	validExprs := []string{
+		"* * * * *",
+		"*/5 * * * *",
+		"0 0 1,15 * *",
+		"30 2 * * 1-5",
+		"0 9-17/2 * * *",
+		"0 0 * * 7",
+		"@daily",
+		"@hourly",
+	}
+	for _, expr := range validExprs {
+		_, err := ParseCron(expr)
+		if err != nil {
+			t.Errorf("Error parsing valid cron expression %s. Err: %v", expr, err)
+		}
+	}
+
+	invalidExprs := []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"*/0 * * * *",
+		"5-2 * * * *",
+		"a * * * *",
+		"@never",
+	}
+	for _, expr := range invalidExprs {
+		_, err := ParseCron(expr)
+		if err == nil {
+			t.Errorf("Invalid cron expression %s parsed without error", expr)
+		}
+	}
+}
+
+// Test computing next schedule time
+func TestCronNext(t *testing.T) {
+	// Saturday, 2015-08-15 10:07:30
+	base := time.Date(2015, time.August, 15, 10, 7, 30, 0, time.UTC)
+
+	testCases := []struct {
+		expr string
+		next time.Time
+	}{
+		{"* * * * *", time.Date(2015, time.August, 15, 10, 8, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2015, time.August, 15, 10, 15, 0, 0, time.UTC)},
+		{"0 * * * *", time.Date(2015, time.August, 15, 11, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2015, time.August, 16, 0, 0, 0, 0, time.UTC)},
+		{"30 2 * * 1-5", time.Date(2015, time.August, 17, 2, 30, 0, 0, time.UTC)},
+		{"0 0 1 * *", time.Date(2015, time.September, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2015, time.August, 16, 0, 0, 0, 0, time.UTC)},
+		{"0 0 1 1 *", time.Date(2016, time.January, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2016, time.February, 29, 0, 0, 0, 0, time.UTC)},
+		// day of month or day of week when both are restricted
+		{"0 0 20 * 1", time.Date(2015, time.August, 17, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tc := range testCases {
+		sched, err := ParseCron(tc.expr)
+		if err != nil {
+			t.Fatalf("Error parsing cron expression %s. Err: %v", tc.expr, err)
+		}
+
+		next := sched.Next(base)
+		if !next.Equal(tc.next) {
+			t.Errorf("Cron %s: expected next %v, got %v", tc.expr, tc.next, next)
+		}
+	}
+
+	// Impossible date should return zero time
+	sched, _ := ParseCron("0 0 31 2 *")
+	if !sched.Next(base).IsZero() {
+		t.Errorf("Expected no match for Feb 31")
+	}
+}
@@ -0,0 +1,137 @@
+package netCtrler
+
+// This file implements VLAN and VXLAN encap of networks. VLAN networks get a
+// VLAN id from a configurable range and are bridged onto physical uplink.
+// VXLAN networks get a VNI from a 24 bit pool and each node maps them to a
+// local VLAN
+
+import (
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// Range of VLAN ids for VLAN networks. Can be set with SYMPHONY_VLAN_RANGE=start-end.
+// Ids are derived from the range start, so it should not change once VLAN networks exist
+var VlanRangeStart uint16 = 100
+var VlanRangeEnd uint16 = 1999
+
+// Environment variable to set the VLAN range
+const vlanRangeEnv = "SYMPHONY_VLAN_RANGE"
+
+// VNIs are 24 bits. VNI 0 is invalid and VNI 1 belongs to default network
+const firstVni = 2
+const maxVnis = (1 << 24) - firstVni
+
+// Supported encaps
+const (
+	encapVlan  = "vlan"
+	encapVxlan = "vxlan"
+)
+
+// Read VLAN range from environment and add the encap resources
+func initEncap() error {
+	if rangeStr := os.Getenv(vlanRangeEnv); rangeStr != "" {
+		start, end, err := parseVlanRange(rangeStr)
+		if err != nil {
+			log.Errorf("Invalid %s %s. Err: %v", vlanRangeEnv, rangeStr, err)
+			return err
+		}
+		VlanRangeStart = start
+		VlanRangeEnd = end
+	}
+
+	log.Infof("Using VLAN range %d-%d", VlanRangeStart, VlanRangeEnd)
+
+	// Add the VLAN and VNI resources. This grows them if the range was extended
+	err := addNetRsrcProvider("vlan", "global", float64(VlanRangeEnd-VlanRangeStart+1))
+	if err != nil {
+		log.Errorf("Error adding vlan resource. Err: %v", err)
+		return err
+	}
+	err = addNetRsrcProvider("vni", "global", maxVnis)
+	if err != nil {
+		log.Errorf("Error adding vni resource. Err: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+// Parse a VLAN range of the form start-end
+func parseVlanRange(rangeStr string) (uint16, uint16, error) {
+	ids := strings.SplitN(rangeStr, "-", 2)
+	if len(ids) != 2 {
+		return 0, 0, errors.New("VLAN range must be of the form start-end")
+	}
+
+	start, err := strconv.ParseUint(strings.TrimSpace(ids[0]), 10, 16)
+	if err != nil {
+		return 0, 0, err
+	}
+	end, err := strconv.ParseUint(strings.TrimSpace(ids[1]), 10, 16)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	// VLAN 0 is reserved and VLAN 1 is used by default network
+	if (start < 2) || (end > 4094) || (start > end) {
+		return 0, 0, errors.New("VLAN range must be within 2-4094")
+	}
+
+	return uint16(start), uint16(end), nil
+}
+
+// Allocate VLAN id or VNI for the network. Allocation returns the existing
+// id if network already had one
+func (self *Network) allocEncap() error {
+	// Default network is always VXLAN with VNI 1. Nodes create it on their own
+	if self.Name == "default" {
+		self.NetSpec.Encap = encapVxlan
+		self.NetSpec.VlanId = 1
+		self.NetSpec.Vni = 1
+		return nil
+	}
+
+	switch self.NetSpec.Encap {
+	case encapVlan:
+		vlanIdx, err := allocNetRsrc("vlan", "global", self.Name)
+		if err != nil {
+			log.Errorf("Error allocating vlan for network %s. Err: %v", self.Name, err)
+			return err
+		}
+		self.NetSpec.VlanId = VlanRangeStart + uint16(vlanIdx)
+		self.NetSpec.Vni = 0
+
+	case encapVxlan:
+		vniIdx, err := allocNetRsrc("vni", "global", self.Name)
+		if err != nil {
+			log.Errorf("Error allocating vni for network %s. Err: %v", self.Name, err)
+			return err
+		}
+		// Each node picks its own local VLAN for the VNI
+		self.NetSpec.VlanId = 0
+		self.NetSpec.Vni = uint32(vniIdx + firstVni)
+
+	default:
+		log.Errorf("Unknown encap %s for network %s", self.NetSpec.Encap, self.Name)
+		return errors.New("Unknown encap " + self.NetSpec.Encap)
+	}
+
+	return nil
+}
+
+// Release VLAN id or VNI of the network
+func (self *Network) freeEncap() error {
+	switch self.NetSpec.Encap {
+	case encapVlan:
+		return freeNetRsrc("vlan", "global", self.Name)
+	case encapVxlan:
+		return freeNetRsrc("vni", "global", self.Name)
+	}
+
+	return nil
+}
@@ -1,13 +1,19 @@
 package netCtrler
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
 	"net"
 	"strconv"
+	"strings"
+	"sync"
 
 	log "github.com/Sirupsen/logrus"
+	"github.com/contiv/objmodel/objdb"
 	"github.com/contiv/ofnet"
 	"github.com/contiv/symphony/pkg/altaspec"
+	"github.com/contiv/symphony/pkg/ipam"
 	"github.com/contiv/symphony/pkg/rsrcMgr"
 )
 
@@ -17,6 +23,7 @@ type EndPoint struct {
 	NetworkName string           // Name of the network this endpoint is in
 	MacAddr     net.HardwareAddr // Mac address for the endpoint
 	IPv4Addr    net.IP           // IPv4 address assigned to this endpoint
+	IPv6Addr    net.IP           // IPv6 address assigned to this endpoint
 }
 
 // Network state
@@ -25,23 +32,40 @@ type Network struct {
 	NetworkId   uint64               // Unique Id allocated to this network
 	IPv4Subnet  net.IPNet            // IP Subnet for this network
 	IPv4Gateway net.IP               // Default IPv4 gateway
+	IPv4Ipam    ipam.PoolSpec        // IPv4 address management config
+	ipv4Pool    *ipam.Pool           // IPv4 address pool
+	IPv6Subnet  net.IPNet            // IPv6 prefix. Empty for IPv4 only networks
+	IPv6Gateway net.IP               // Default IPv6 gateway
+	IPv6Ipam    ipam.PoolSpec        // IPv6 address management config
+	ipv6Pool    *ipam.Pool           // IPv6 address pool
 	DnsAddr     []net.IP             // DNS addresses
 	NetSpec     altaspec.AltaNetSpec // Network parameters
-	EndPoints   map[string]*EndPoint // List of end points in this network
+	EndPoints   map[string]*EndPoint `json:"-"` // List of end points in this network. Saved separately
 }
 
+// Mac addresses have 24 bits for unique id
+const maxMacAddrs = 1 << 24
+
+// Datapath forwards IPv6 traffic. vrouter datapath routes only IPv4 and has no
+// neighbor discovery, so networks with IPv6 prefixes are rejected till it does
+var DatapathIPv6 = false
+
 var netCtrl struct {
+	cdb             objdb.ObjdbApi      // conf store
 	networkDb       map[string]*Network // DB of networks
 	IPv4SubnetStart net.IP              // Starting IP subnet
 	DnsAddr         []net.IP            // DNS server list
 	ofnetMaster     *ofnet.OfnetMaster  // ofnet master
+	mutex           sync.Mutex          // Lock for network and endpoint DBs
 }
 
 // Initialize network controller
 // Network controller stores all resources in rsrcMgr. So, in a way netCtrler
-// is just a wrapper around rsrcMgr
-func Init() {
+// is just a wrapper around rsrcMgr. Networks and endpoints are also saved to
+// conf store and rebuilt from it along with rsrcMgr allocations
+func Init(cdb objdb.ObjdbApi) {
 	// Setup basic state
+	netCtrl.cdb = cdb
 	netCtrl.networkDb = make(map[string]*Network)
 	netCtrl.IPv4SubnetStart = net.ParseIP("10.200.1.0")
 	netCtrl.DnsAddr = []net.IP{net.ParseIP("4.4.4.4"), net.ParseIP("8.8.8.8")}
@@ -49,7 +73,7 @@ func Init() {
 	// Initialize ofnet master
 	netCtrl.ofnetMaster = ofnet.NewOfnetMaster(ofnet.OFNET_MASTER_PORT)
 
-	log.Infof("netCtrl: %#v", netCtrl)
+	log.Infof("netCtrl: subnet start %v, dns %v", netCtrl.IPv4SubnetStart, netCtrl.DnsAddr)
 
 	// Check if global network resources are created/restored
 	rsrcProvider := rsrcMgr.FindResourceProvider("network", "global")
@@ -60,16 +84,30 @@ func Init() {
 		if err != nil {
 			log.Fatalf("Error adding global network resource. Err: %v", err)
 		}
+	}
 
-		// Add global mac address resource
-		// Support 20K mac addresses for now
-		err = addNetRsrcProvider("macaddr", "global", 20000)
-		if err != nil {
-			log.Fatalf("Error adding global macaddr resource. Err: %v", err)
-		}
+	// Add global mac address resource. This grows the resource restored
+	// from older versions that supported only 20K mac addresses
+	err := addNetRsrcProvider("macaddr", "global", maxMacAddrs)
+	if err != nil {
+		log.Fatalf("Error adding global macaddr resource. Err: %v", err)
+	}
+
+	// Add VLAN and VNI resources
+	err = initEncap()
+	if err != nil {
+		log.Fatalf("Error adding network encap resources. Err: %v", err)
+	}
 
-		// Create the default network
-		_, err = NewNetwork("default")
+	// Rebuild networks and endpoints we had before
+	err = restoreNetworks()
+	if err != nil {
+		log.Errorf("Error restoring networks. Err: %v", err)
+	}
+
+	// Create the default network if it doesnt exist
+	if netCtrl.networkDb["default"] == nil {
+		_, err = NewNetwork("default", "", ipam.PoolSpec{}, ipam.PoolSpec{})
 		if err != nil {
 			log.Fatalf("Error creating default network. Err: %v", err)
 		}
@@ -97,6 +135,20 @@ func addNetRsrcProvider(rType string, prvdKey string, numRsrc float64) error {
 	return nil
 }
 
+// Remove a network resource provider
+func removeNetRsrcProvider(rType string, prvdKey string) error {
+	// provider info
+	provider := []rsrcMgr.ResourceProvide{
+		{
+			Type:     rType,
+			Provider: prvdKey,
+			UnitType: "descrete",
+		},
+	}
+
+	return rsrcMgr.RemoveResourceProvider(provider)
+}
+
 // Allocate a single network resource
 func allocNetRsrc(rType, prvdKey, userKey string) (uint64, error) {
 	// What to allocate
@@ -119,13 +171,42 @@ func allocNetRsrc(rType, prvdKey, userKey string) (uint64, error) {
 	return respRsrsList[0].RsrcIndexes[0], nil
 }
 
-// Create a new named network
-func NewNetwork(name string) (*Network, error) {
-	var err error
-	// Create the network
-	network := new(Network)
-	network.Name = name
+// Free a single network resource
+func freeNetRsrc(rType, prvdKey, userKey string) error {
+	// What to free
+	rsrcList := []rsrcMgr.ResourceUse{
+		{
+			Type:     rType,
+			Provider: prvdKey,
+			UserKey:  userKey,
+			NumRsrc:  1,
+		},
+	}
+
+	// Free the resource
+	return rsrcMgr.FreeResources(rsrcList)
+}
+
+// Create a new named network. Encap is vlan or vxlan, and defaults to vxlan.
+// IPv4 subnet is derived from network id when address management config
+// doesnt specify one. Network is dual stack when IPv6 config has a subnet,
+// which requires a datapath that forwards IPv6
+func NewNetwork(name, encap string, ipv4Spec, ipv6Spec ipam.PoolSpec) (*Network, error) {
+	// Dont hand out IPv6 addresses nobody can reach
+	if (ipv6Spec.Subnet != "") && !DatapathIPv6 {
+		log.Errorf("Can not create network %s with IPv6 prefix %s. Datapath does not forward IPv6",
+			name, ipv6Spec.Subnet)
+		return nil, errors.New("IPv6 is not supported by the datapath")
+	}
 
+	netCtrl.mutex.Lock()
+	defer netCtrl.mutex.Unlock()
+
+	return newNetwork(name, encap, ipv4Spec, ipv6Spec)
+}
+
+// Create a new named network. Caller must hold the lock
+func newNetwork(name, encap string, ipv4Spec, ipv6Spec ipam.PoolSpec) (*Network, error) {
 	// Check if the named network already exists
 	if netCtrl.networkDb[name] != nil {
 		log.Errorf("Network %s already exists", name)
@@ -133,42 +214,100 @@ func NewNetwork(name string) (*Network, error) {
 	}
 
 	// Allocate a new network Id
-	network.NetworkId, err = allocNetRsrc("network", "global", name)
+	networkId, err := allocNetRsrc("network", "global", name)
 	if err != nil {
 		log.Errorf("Error allocating network id for %s. Err: %v", name, err)
 		return nil, err
 	}
 
-	// Initialize Netspec
-	// FIXME: initialize both Vlan and VNI to be same as networkId
-	network.NetSpec = altaspec.AltaNetSpec{
-		NetworkName: name,
-		VlanId:      uint16(network.NetworkId + 1),
-		Vni:         uint32(network.NetworkId + 1),
+	// Derive network parameters from network id
+	network, err := buildNetwork(name, networkId, encap, ipv4Spec, ipv6Spec)
+	if err == nil {
+		err = checkOverlap(network)
+	}
+	if err == nil {
+		err = network.allocEncap()
+	}
+	if err != nil {
+		log.Errorf("Error creating network %s. Err: %v", name, err)
+		freeNetRsrc("network", "global", name)
+		return nil, err
 	}
 
-	// Create subnet address resource for the network
-	// assuming /24 and reserve .0, .1 & .255 addresses
-	err = addNetRsrcProvider("subnetAddr", name, 253)
+	// Create subnet address resources for the network
+	err = network.addSubnetProviders()
 	if err != nil {
 		log.Fatalf("Error adding global subnet resource. Err: %v", err)
 	}
 
-	// Derive subnet IP addr. netmask is always set to /24
+	// Store it in global DB
+	netCtrl.networkDb[name] = network
+
+	// Save it to conf store
+	err = saveNetwork(network)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Infof("Created network: %+v", network)
+
+	// done
+	return network, nil
+}
+
+// Build network state from its network id and address management config
+func buildNetwork(name string, networkId uint64, encap string, ipv4Spec, ipv6Spec ipam.PoolSpec) (*Network, error) {
+	network := new(Network)
+	network.Name = name
+	network.NetworkId = networkId
+
+	// Initialize Netspec. VLAN id and VNI are allocated based on encap
+	if encap == "" {
+		encap = encapVxlan
+	}
+	network.NetSpec = altaspec.AltaNetSpec{
+		NetworkName: name,
+		Encap:       encap,
+	}
+
+	// Derive a /24 subnet from network id if we were not given one
 	// WARNING: there is a dangerous assumption on IP addresses here
-	netLsb := byte(network.NetworkId % 256)
-	netMsb := byte(network.NetworkId / 256)
-	netSubnet := net.ParseIP(netCtrl.IPv4SubnetStart.String()) // copy the slice
-	netSubnet[13] += netMsb
-	netSubnet[14] += netLsb
-	network.IPv4Subnet = net.IPNet{
-		IP:   netSubnet,
-		Mask: net.IPv4Mask(255, 255, 255, 0),
+	if ipv4Spec.Subnet == "" {
+		netLsb := byte(network.NetworkId % 256)
+		netMsb := byte(network.NetworkId / 256)
+		netSubnet := net.ParseIP(netCtrl.IPv4SubnetStart.String()) // copy the slice
+		netSubnet[13] += netMsb
+		netSubnet[14] += netLsb
+		ipv4Spec.Subnet = netSubnet.String() + "/24"
 	}
 
-	// Default GW is at 10.x.x.1
-	network.IPv4Gateway = netSubnet
-	network.IPv4Gateway[15] = 1
+	// Create the address pool. Default GW is the first address unless specified
+	pool, err := ipam.NewPool(ipv4Spec)
+	if err != nil {
+		return nil, err
+	}
+	if pool.Subnet.IP.To4() == nil {
+		return nil, errors.New("Subnet " + ipv4Spec.Subnet + " is not an IPv4 subnet")
+	}
+	network.IPv4Ipam = ipv4Spec
+	network.ipv4Pool = pool
+	network.IPv4Subnet = pool.Subnet
+	network.IPv4Gateway = pool.Gateway
+
+	// Create IPv6 address pool if we have a prefix
+	if ipv6Spec.Subnet != "" {
+		pool, err = ipam.NewPool(ipv6Spec)
+		if err != nil {
+			return nil, err
+		}
+		if pool.Subnet.IP.To4() != nil {
+			return nil, errors.New("Subnet " + ipv6Spec.Subnet + " is not an IPv6 subnet")
+		}
+		network.IPv6Ipam = ipv6Spec
+		network.ipv6Pool = pool
+		network.IPv6Subnet = pool.Subnet
+		network.IPv6Gateway = pool.Gateway
+	}
 
 	// DNS addresses from global state
 	network.DnsAddr = netCtrl.DnsAddr
@@ -176,17 +315,66 @@ func NewNetwork(name string) (*Network, error) {
 	// init endpoint db
 	network.EndPoints = make(map[string]*EndPoint)
 
-	// Store it in global DB
-	netCtrl.networkDb[name] = network
+	return network, nil
+}
 
-	log.Infof("Created network: %+v", network)
+// Make sure network's subnets dont overlap with any other network
+func checkOverlap(network *Network) error {
+	for _, other := range netCtrl.networkDb {
+		if other.Name == network.Name {
+			continue
+		}
+		if ipam.Overlaps(&network.IPv4Subnet, &other.IPv4Subnet) {
+			log.Errorf("Subnet %s of network %s overlaps with %s of network %s", network.IPv4Subnet.String(),
+				network.Name, other.IPv4Subnet.String(), other.Name)
+			return errors.New("Subnet overlaps with network " + other.Name)
+		}
+		if ipam.Overlaps(&network.IPv6Subnet, &other.IPv6Subnet) {
+			log.Errorf("Prefix %s of network %s overlaps with %s of network %s", network.IPv6Subnet.String(),
+				network.Name, other.IPv6Subnet.String(), other.Name)
+			return errors.New("IPv6 prefix overlaps with network " + other.Name)
+		}
+	}
 
-	// done
-	return network, nil
+	return nil
+}
+
+// Check if IPv6 addresses are allocated sequentially from rsrcMgr
+func (self *Network) seqIPv6() bool {
+	return (self.ipv6Pool != nil) && !self.ipv6Pool.Spec.Slaac
+}
+
+// Create subnet address resources for the network if they dont exist.
+// Resources are sized from the subnets
+func (self *Network) addSubnetProviders() error {
+	if rsrcMgr.FindResourceProvider("subnetAddr", self.Name) == nil {
+		err := addNetRsrcProvider("subnetAddr", self.Name, float64(self.ipv4Pool.Size()))
+		if err != nil {
+			return err
+		}
+	}
+
+	// SLAAC addresses come from mac address and dont need a resource
+	if self.seqIPv6() && (rsrcMgr.FindResourceProvider("subnetAddr6", self.Name) == nil) {
+		err := addNetRsrcProvider("subnetAddr6", self.Name, float64(self.ipv6Pool.Size()))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
 
 // Find the named network
 func FindNetwork(name string) (*Network, error) {
+	netCtrl.mutex.Lock()
+	defer netCtrl.mutex.Unlock()
+
+	return findNetwork(name)
+}
+
+// Find the named network. Caller must hold the lock
+func findNetwork(name string) (*Network, error) {
 	if netCtrl.networkDb[name] == nil {
 		return nil, errors.New("Network not found")
 	}
@@ -194,8 +382,80 @@ func FindNetwork(name string) (*Network, error) {
 	return netCtrl.networkDb[name], nil
 }
 
+// Delete a network and release its resources. Network must not have any end points
+func DeleteNetwork(name string) error {
+	netCtrl.mutex.Lock()
+	defer netCtrl.mutex.Unlock()
+
+	network := netCtrl.networkDb[name]
+	if network == nil {
+		log.Errorf("Network %s not found", name)
+		return errors.New("Network not found")
+	}
+
+	// Altas without networks are attached to default network
+	if name == "default" {
+		log.Errorf("Can not delete default network")
+		return errors.New("Default network can not be deleted")
+	}
+
+	// Make sure no one is using it
+	if len(network.EndPoints) != 0 {
+		log.Errorf("Network %s still has %d end points", name, len(network.EndPoints))
+		return fmt.Errorf("Network %s still has %d end points", name, len(network.EndPoints))
+	}
+	rsrcTypes := []string{"subnetAddr"}
+	if network.seqIPv6() {
+		rsrcTypes = append(rsrcTypes, "subnetAddr6")
+	}
+	for _, rType := range rsrcTypes {
+		if len(rsrcUserIndexes(rType, name)) != 0 {
+			log.Errorf("Network %s still has %s allocations", name, rType)
+			return errors.New("Network still has addresses allocated")
+		}
+	}
+
+	// Remove subnet address resources
+	for _, rType := range rsrcTypes {
+		if rsrcMgr.FindResourceProvider(rType, name) == nil {
+			continue
+		}
+		err := removeNetRsrcProvider(rType, name)
+		if err != nil {
+			log.Errorf("Error removing %s resource of network %s. Err: %v", rType, name, err)
+			return err
+		}
+	}
+
+	// Release the VLAN id or VNI
+	err := network.freeEncap()
+	if err != nil {
+		log.Errorf("Error freeing %s encap of %s. Err: %v", network.NetSpec.Encap, name, err)
+	}
+
+	// Release the network id
+	err = freeNetRsrc("network", "global", name)
+	if err != nil {
+		log.Errorf("Error freeing network id of %s. Err: %v", name, err)
+	}
+
+	// Remove it from DB and conf store
+	delete(netCtrl.networkDb, name)
+	err = delNetwork(name)
+	if err != nil {
+		log.Errorf("Error deleting network %s from conf store. Err: %v", name, err)
+	}
+
+	log.Infof("Deleted network %s", name)
+
+	return nil
+}
+
 // Return a list of all existing networks
 func ListNetwork(name string) []*Network {
+	netCtrl.mutex.Lock()
+	defer netCtrl.mutex.Unlock()
+
 	netList := make([]*Network, 0)
 
 	for _, net := range netCtrl.networkDb {
@@ -207,12 +467,42 @@ func ListNetwork(name string) []*Network {
 
 // Create a new network end point
 func (self *Network) NewEndPoint(epKey string) (*EndPoint, error) {
+	netCtrl.mutex.Lock()
+	defer netCtrl.mutex.Unlock()
+
+	return self.newEndPoint(epKey)
+}
+
+// Create a new network end point. Caller must hold the lock
+func (self *Network) newEndPoint(epKey string) (*EndPoint, error) {
 	// If the end point already exists, just return it
 	if self.EndPoints[epKey] != nil {
 		return self.EndPoints[epKey], nil
 	}
 
-	// Create the end point state
+	// Allocate the addresses. Release whatever we got if it fails midway
+	endPoint, err := self.allocEndPoint(epKey)
+	if err != nil {
+		self.freeEndPoint(epKey)
+		return nil, err
+	}
+
+	// store it in db
+	self.EndPoints[epKey] = endPoint
+
+	// Save it to conf store
+	err = saveEndPoint(endPoint)
+	if err != nil {
+		return nil, err
+	}
+
+	// done
+	return endPoint, nil
+}
+
+// Allocate addresses of an end point and build its state. Allocations
+// return existing addresses if the end point already had them
+func (self *Network) allocEndPoint(epKey string) (*EndPoint, error) {
 	endPoint := new(EndPoint)
 	endPoint.EPKey = epKey
 	endPoint.NetworkName = self.Name
@@ -223,6 +513,7 @@ func (self *Network) NewEndPoint(epKey string) (*EndPoint, error) {
 		log.Errorf("Error allocating mac address for %s/%s", self.Name, epKey)
 		return nil, err
 	}
+
 	// Our grand mac addr allocation scheme is to allocate a unique id and then
 	// form a mac addr 02:02:02.xx.xx.xx where last 3 bytes come from unique id
 	// Note that x2.xx.xx.xx.xx.xx address is a locally administered mac addr
@@ -236,28 +527,103 @@ func (self *Network) NewEndPoint(epKey string) (*EndPoint, error) {
 		return nil, err
 	}
 
-	// IPv4 address scheme is subnet.xx where xx is unique id + 2
-	// subnet.0 and subnet.255 are reserved. subnet.1 is used by default gw
-	endPoint.IPv4Addr = self.IPv4Subnet.IP
-	endPoint.IPv4Addr[15] = byte(ipId + 2)
+	// IPv4 address is picked from the pool by its unique id
+	endPoint.IPv4Addr, err = self.ipv4Pool.IndexToIP(ipId)
+	if err != nil {
+		log.Errorf("Error getting IP address for %s/%s. Err: %v", self.Name, epKey, err)
+		return nil, err
+	}
 
-	// store it in db
-	self.EndPoints[epKey] = endPoint
+	// IPv6 address is derived from mac address or allocated sequentially
+	if self.seqIPv6() {
+		ip6Id, err := allocNetRsrc("subnetAddr6", self.Name, epKey)
+		if err != nil {
+			log.Errorf("Error allocating IPv6 address for %s/%s", self.Name, epKey)
+			return nil, err
+		}
+		endPoint.IPv6Addr, err = self.ipv6Pool.IndexToIP(ip6Id)
+		if err != nil {
+			log.Errorf("Error getting IPv6 address for %s/%s. Err: %v", self.Name, epKey, err)
+			return nil, err
+		}
+	} else if self.ipv6Pool != nil {
+		endPoint.IPv6Addr, err = self.ipv6Pool.SlaacAddr(endPoint.MacAddr)
+		if err != nil {
+			log.Errorf("Error getting IPv6 address for %s/%s. Err: %v", self.Name, epKey, err)
+			return nil, err
+		}
+	}
 
-	// done
 	return endPoint, nil
 }
 
+// Delete a network end point and release its mac and IP address
+func (self *Network) DeleteEndPoint(epKey string) error {
+	netCtrl.mutex.Lock()
+	defer netCtrl.mutex.Unlock()
+
+	return self.deleteEndPoint(epKey)
+}
+
+// Delete a network end point. Caller must hold the lock
+func (self *Network) deleteEndPoint(epKey string) error {
+	// Make sure the end point exists
+	if self.EndPoints[epKey] == nil {
+		log.Errorf("End point %s not found in network %s", epKey, self.Name)
+		return errors.New("End point not found")
+	}
+
+	// Release the addresses
+	self.freeEndPoint(epKey)
+
+	// remove it from db
+	delete(self.EndPoints, epKey)
+
+	// remove it from conf store
+	err := delEndPoint(epKey)
+	if err != nil {
+		log.Errorf("Error deleting end point %s/%s from conf store. Err: %v", self.Name, epKey, err)
+	}
+
+	log.Infof("Deleted end point %s/%s", self.Name, epKey)
+
+	return nil
+}
+
+// Release mac and IP addresses of an end point
+func (self *Network) freeEndPoint(epKey string) {
+	// Release the mac address
+	err := freeNetRsrc("macaddr", "global", epKey)
+	if err != nil {
+		log.Errorf("Error freeing mac address for %s/%s. Err: %v", self.Name, epKey, err)
+	}
+
+	// Release the IP address
+	err = freeNetRsrc("subnetAddr", self.Name, epKey)
+	if err != nil {
+		log.Errorf("Error freeing IP address for %s/%s. Err: %v", self.Name, epKey, err)
+	}
+	if self.seqIPv6() {
+		err = freeNetRsrc("subnetAddr6", self.Name, epKey)
+		if err != nil {
+			log.Errorf("Error freeing IPv6 address for %s/%s. Err: %v", self.Name, epKey, err)
+		}
+	}
+}
+
 // Return a endpoint from network name
 func CreateAltaEndpoint(altaId string, netName string, ifNum int) (*altaspec.AltaEndpoint, error) {
 	var network *Network
 	var err error
 
+	netCtrl.mutex.Lock()
+	defer netCtrl.mutex.Unlock()
+
 	// find or create the network
-	network, _ = FindNetwork(netName)
+	network, _ = findNetwork(netName)
 	if network == nil {
 		// Network doesnt exist, create it
-		network, err = NewNetwork(netName)
+		network, err = newNetwork(netName, "", ipam.PoolSpec{}, ipam.PoolSpec{})
 		if err != nil {
 			log.Errorf("Error creating network %s. Err: %v", netName, err)
 			return nil, err
@@ -267,7 +633,7 @@ func CreateAltaEndpoint(altaId string, netName string, ifNum int) (*altaspec.Alt
 	epKey := altaId + "." + strconv.Itoa(ifNum)
 
 	// Create an end point on the network
-	endPoint, err := network.NewEndPoint(epKey)
+	endPoint, err := network.newEndPoint(epKey)
 	if err != nil {
 		log.Errorf("Error creating end point %s/%s", netName, epKey)
 		return nil, err
@@ -278,12 +644,276 @@ func CreateAltaEndpoint(altaId string, netName string, ifNum int) (*altaspec.Alt
 		NetworkName:     network.Name,
 		IntfMacAddr:     endPoint.MacAddr.String(),
 		IntfIpv4Addr:    endPoint.IPv4Addr.String(),
-		IntfIpv4Masklen: 24,
+		IntfIpv4Masklen: network.ipv4Pool.PrefixLen(),
 		Ipv4Gateway:     network.IPv4Gateway.String(),
 	}
 
+	// Add IPv6 address on dual stack networks
+	if endPoint.IPv6Addr != nil {
+		altaNetIf.IntfIpv6Addr = endPoint.IPv6Addr.String()
+		altaNetIf.IntfIpv6Masklen = network.ipv6Pool.PrefixLen()
+		altaNetIf.Ipv6Gateway = network.IPv6Gateway.String()
+	}
+
 	log.Infof("Created Alta interface: %+v", altaNetIf)
 
 	// done
 	return &altaNetIf, nil
 }
+
+// Delete all end points of an alta container in a network
+func DeleteAltaEndpoint(altaId string, netName string) error {
+	netCtrl.mutex.Lock()
+	defer netCtrl.mutex.Unlock()
+
+	network, err := findNetwork(netName)
+	if err != nil {
+		log.Errorf("Network %s not found while deleting endpoint for %s", netName, altaId)
+		return err
+	}
+
+	// End point keys are of the form altaId.ifNum
+	epPrefix := altaId + "."
+	for epKey := range network.EndPoints {
+		if strings.HasPrefix(epKey, epPrefix) {
+			err = network.deleteEndPoint(epKey)
+			if err != nil {
+				log.Errorf("Error deleting end point %s/%s. Err: %v", netName, epKey, err)
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Save a network to conf store
+func saveNetwork(network *Network) error {
+	// If there is no conf store, just ignore it. mainly for unit testing
+	if netCtrl.cdb == nil {
+		return nil
+	}
+
+	storeKey := "network/" + network.Name
+	err := netCtrl.cdb.SetObj(storeKey, network)
+	if err != nil {
+		log.Errorf("Error storing network %s. Err: %v", network.Name, err)
+		return err
+	}
+
+	return nil
+}
+
+// Delete a network from conf store
+func delNetwork(name string) error {
+	// If there is no conf store, just ignore it. mainly for unit testing
+	if netCtrl.cdb == nil {
+		return nil
+	}
+
+	return netCtrl.cdb.DelObj("network/" + name)
+}
+
+// Save an end point to conf store
+func saveEndPoint(endPoint *EndPoint) error {
+	// If there is no conf store, just ignore it. mainly for unit testing
+	if netCtrl.cdb == nil {
+		return nil
+	}
+
+	storeKey := "endpoint/" + endPoint.EPKey
+	err := netCtrl.cdb.SetObj(storeKey, endPoint)
+	if err != nil {
+		log.Errorf("Error storing end point %s. Err: %v", endPoint.EPKey, err)
+		return err
+	}
+
+	return nil
+}
+
+// Delete an end point from conf store
+func delEndPoint(epKey string) error {
+	// If there is no conf store, just ignore it. mainly for unit testing
+	if netCtrl.cdb == nil {
+		return nil
+	}
+
+	return netCtrl.cdb.DelObj("endpoint/" + epKey)
+}
+
+// Read saved networks and end points from conf store
+func readSavedState() (map[string]*Network, map[string]*EndPoint, error) {
+	networks := make(map[string]*Network)
+	endPoints := make(map[string]*EndPoint)
+
+	// If there is no conf store, nothing was saved
+	if netCtrl.cdb == nil {
+		return networks, endPoints, nil
+	}
+
+	// Read the networks
+	jsonArr, err := netCtrl.cdb.ListDir("network")
+	if err != nil {
+		log.Errorf("Error getting networks from cdb. Err: %v", err)
+		return nil, nil, err
+	}
+	for _, elemStr := range jsonArr {
+		var network Network
+		err = json.Unmarshal([]byte(elemStr), &network)
+		if err != nil {
+			log.Errorf("Error parsing object %s, Err %v", elemStr, err)
+			return nil, nil, err
+		}
+
+		networks[network.Name] = &network
+	}
+
+	// Read the end points
+	jsonArr, err = netCtrl.cdb.ListDir("endpoint")
+	if err != nil {
+		log.Errorf("Error getting end points from cdb. Err: %v", err)
+		return nil, nil, err
+	}
+	for _, elemStr := range jsonArr {
+		var endPoint EndPoint
+		err = json.Unmarshal([]byte(elemStr), &endPoint)
+		if err != nil {
+			log.Errorf("Error parsing object %s, Err %v", elemStr, err)
+			return nil, nil, err
+		}
+
+		endPoints[endPoint.EPKey] = &endPoint
+	}
+
+	return networks, endPoints, nil
+}
+
+// Return resource index allocated to each user of a network resource provider
+func rsrcUserIndexes(rType, prvdKey string) map[string]uint64 {
+	userIndexes := make(map[string]uint64)
+
+	provider := rsrcMgr.FindResourceProvider(rType, prvdKey)
+	if provider == nil {
+		return userIndexes
+	}
+	for userKey, user := range provider.RsrcUsers {
+		if len(user.RsrcIndexes) > 0 {
+			userIndexes[userKey] = user.RsrcIndexes[0]
+		}
+	}
+
+	return userIndexes
+}
+
+// Rebuild networks and end points after a restart or master failover.
+// Allocations in rsrcMgr are the source of truth for network ids, mac and IP
+// addresses. Saved state fills in whatever was not allocated yet
+func restoreNetworks() error {
+	log.Infof("Restoring networks..")
+
+	savedNets, savedEps, err := readSavedState()
+	if err != nil {
+		return err
+	}
+
+	// Networks that have an id allocated or were saved
+	networkIds := rsrcUserIndexes("network", "global")
+	for name := range savedNets {
+		if _, ok := networkIds[name]; !ok {
+			networkIds[name] = 0
+		}
+	}
+
+	for name := range networkIds {
+		// Allocate an id if we saved the network before allocating one.
+		// This returns the existing id if it was allocated
+		networkId, err := allocNetRsrc("network", "global", name)
+		if err != nil {
+			log.Errorf("Error allocating network id for %s. Err: %v", name, err)
+			continue
+		}
+
+		// Rebuild the network with address management config we saved
+		var encap string
+		var ipv4Spec, ipv6Spec ipam.PoolSpec
+		if savedNets[name] != nil {
+			encap = savedNets[name].NetSpec.Encap
+			ipv4Spec = savedNets[name].IPv4Ipam
+			ipv6Spec = savedNets[name].IPv6Ipam
+		}
+		network, err := buildNetwork(name, networkId, encap, ipv4Spec, ipv6Spec)
+		if err == nil {
+			err = network.allocEncap()
+		}
+		if err != nil {
+			log.Errorf("Error rebuilding network %s. Err: %v", name, err)
+			continue
+		}
+
+		// Make sure subnet address resources exist
+		err = network.addSubnetProviders()
+		if err != nil {
+			log.Errorf("Error adding subnet resource for %s. Err: %v", name, err)
+			continue
+		}
+
+		netCtrl.networkDb[name] = network
+
+		// End points that have an IP address allocated or were saved
+		epKeys := rsrcUserIndexes("subnetAddr", name)
+		for epKey, endPoint := range savedEps {
+			if endPoint.NetworkName == name {
+				if _, ok := epKeys[epKey]; !ok {
+					epKeys[epKey] = 0
+				}
+			}
+		}
+
+		for epKey := range rsrcUserIndexes("subnetAddr6", name) {
+			epKeys[epKey] = 0
+		}
+
+		for epKey := range epKeys {
+			endPoint, err := network.allocEndPoint(epKey)
+			if err != nil {
+				log.Errorf("Error rebuilding end point %s/%s. Err: %v", name, epKey, err)
+				continue
+			}
+			network.EndPoints[epKey] = endPoint
+			saveEndPoint(endPoint)
+		}
+
+		saveNetwork(network)
+
+		log.Infof("Restored network %s with %d end points", name, len(network.EndPoints))
+	}
+
+	// Free mac addresses that dont belong to any end point
+	for epKey := range rsrcUserIndexes("macaddr", "global") {
+		if findEndPoint(epKey) == nil {
+			log.Infof("Freeing orphan mac address of end point %s", epKey)
+			freeNetRsrc("macaddr", "global", epKey)
+		}
+	}
+
+	// Remove saved end points whose network is gone
+	for epKey := range savedEps {
+		if findEndPoint(epKey) == nil {
+			log.Infof("Removing stale end point %s", epKey)
+			delEndPoint(epKey)
+		}
+	}
+
+	return nil
+}
+
+// Find an end point in any network
+func findEndPoint(epKey string) *EndPoint {
+	for _, network := range netCtrl.networkDb {
+		if network.EndPoints[epKey] != nil {
+			return network.EndPoints[epKey]
+		}
+	}
+
+	return nil
+}
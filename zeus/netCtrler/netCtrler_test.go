@@ -1,25 +1,44 @@
 package netCtrler
 
 import (
+	"net"
+	"strconv"
+	"sync"
 	"testing"
 
-	"github.com/contiv/symphony/zeus/rsrcMgr"
+	"github.com/contiv/symphony/pkg/ipam"
+	"github.com/contiv/symphony/pkg/rsrcMgr"
 
 	log "github.com/Sirupsen/logrus"
 )
 
+// Ctrler can be initialized only once since it starts ofnet master
+var initOnce sync.Once
+
+func initNetCtrl() {
+	initOnce.Do(func() {
+		// initialize rsrcMgr since we use it for resource allocation
+		rsrcMgr.Init(nil)
+
+		// Initialize the ctrler. This creates the default network
+		Init(nil)
+	})
+}
+
 // Simple test to create a network and add an end point
 func TestAddNetwork(t *testing.T) {
-	// initialize rsrcMgr since we use it for resource allocation
-	rsrcMgr.Init(nil)
+	initNetCtrl()
 
-	// Initialize the ctrler
-	Init()
+	_, err := FindNetwork("default")
+	if err != nil {
+		t.Errorf("Default network was not created. Err: %v", err)
+		return
+	}
 
 	// Create network
-	network, err := NewNetwork("default")
+	network, err := NewNetwork("test", "", ipam.PoolSpec{}, ipam.PoolSpec{})
 	if err != nil {
-		t.Errorf("Error creating network default. Err: %v", err)
+		t.Errorf("Error creating network test. Err: %v", err)
 		return
 	}
 
@@ -33,4 +52,291 @@ func TestAddNetwork(t *testing.T) {
 	}
 
 	log.Infof("Successfully Created endpoint: %+v", ep)
+
+	// Creating the end point should not change the subnet
+	if network.IPv4Subnet.IP.String() != "10.200.2.0" {
+		t.Errorf("Network subnet changed to %s", network.IPv4Subnet.IP.String())
+	}
+
+	// Forget the networks and rebuild them from rsrcMgr allocations
+	netCtrl.networkDb = make(map[string]*Network)
+	err = restoreNetworks()
+	if err != nil {
+		t.Errorf("Error restoring networks. Err: %v", err)
+		return
+	}
+
+	network, err = FindNetwork("test")
+	if err != nil {
+		t.Errorf("Network test was not restored. Err: %v", err)
+		return
+	}
+	restoredEp := network.EndPoints["alta1234.0"]
+	if restoredEp == nil {
+		t.Errorf("End point was not restored")
+		return
+	}
+	if (restoredEp.MacAddr.String() != ep.MacAddr.String()) ||
+		(restoredEp.IPv4Addr.String() != ep.IPv4Addr.String()) {
+		t.Errorf("Restored end point %+v does not match %+v", restoredEp, ep)
+	}
+}
+
+// Test networks with their own subnet and gateway
+func TestNetworkSubnet(t *testing.T) {
+	initNetCtrl()
+
+	network, err := NewNetwork("custom", "", ipam.PoolSpec{
+		Subnet:   "192.168.10.0/23",
+		Gateway:  "192.168.11.254",
+		Excluded: []string{"192.168.10.1-192.168.10.99"},
+	}, ipam.PoolSpec{})
+	if err != nil {
+		t.Fatalf("Error creating network custom. Err: %v", err)
+	}
+
+	altaIf, err := CreateAltaEndpoint("alta5678", "custom", 0)
+	if err != nil {
+		t.Fatalf("Error creating alta endpoint. Err: %v", err)
+	}
+	if (altaIf.IntfIpv4Addr != "192.168.10.100") || (altaIf.IntfIpv4Masklen != 23) ||
+		(altaIf.Ipv4Gateway != "192.168.11.254") {
+		t.Errorf("Unexpected alta endpoint %+v", altaIf)
+	}
+
+	// Subnet should be sized from the prefix length
+	provider := rsrcMgr.FindResourceProvider("subnetAddr", network.Name)
+	if (provider == nil) || (provider.NumRsrc != 410) {
+		t.Errorf("Unexpected subnet address provider %+v", provider)
+	}
+
+	// Overlapping subnets are rejected
+	_, err = NewNetwork("overlap", "", ipam.PoolSpec{Subnet: "192.168.11.0/24"}, ipam.PoolSpec{})
+	if err == nil {
+		t.Errorf("Overlapping network was created")
+	}
+	if _, err := FindNetwork("overlap"); err == nil {
+		t.Errorf("Overlapping network was saved")
+	}
+}
+
+// Test dual stack networks with SLAAC and sequential IPv6 addresses
+func TestDualStack(t *testing.T) {
+	initNetCtrl()
+
+	// IPv6 is rejected unless datapath forwards it
+	_, err := NewNetwork("noipv6", "", ipam.PoolSpec{Subnet: "172.16.4.0/24"},
+		ipam.PoolSpec{Subnet: "fd00:40::/64", Slaac: true})
+	if err == nil {
+		t.Errorf("IPv6 network was created without datapath support")
+	}
+	if _, err := FindNetwork("noipv6"); err == nil {
+		t.Errorf("Rejected network was saved")
+	}
+
+	DatapathIPv6 = true
+	defer func() { DatapathIPv6 = false }()
+
+	_, err = NewNetwork("slaac", "", ipam.PoolSpec{Subnet: "172.16.1.0/24"},
+		ipam.PoolSpec{Subnet: "fd00:10::/64", Slaac: true})
+	if err != nil {
+		t.Fatalf("Error creating network slaac. Err: %v", err)
+	}
+
+	altaIf, err := CreateAltaEndpoint("alta1111", "slaac", 0)
+	if err != nil {
+		t.Fatalf("Error creating alta endpoint. Err: %v", err)
+	}
+	mac, _ := net.ParseMAC(altaIf.IntfMacAddr)
+	expAddr := net.IP{0xfd, 0, 0, 0x10, 0, 0, 0, 0, mac[0] ^ 2, mac[1], mac[2], 0xff, 0xfe, mac[3], mac[4], mac[5]}
+	if (altaIf.IntfIpv6Addr != expAddr.String()) || (altaIf.IntfIpv6Masklen != 64) ||
+		(altaIf.Ipv6Gateway != "fd00:10::1") {
+		t.Errorf("Unexpected alta endpoint %+v", altaIf)
+	}
+
+	network, err := NewNetwork("seq6", "", ipam.PoolSpec{Subnet: "172.16.2.0/24"},
+		ipam.PoolSpec{Subnet: "fd00:20::/112", Gateway: "fd00:20::ffff"})
+	if err != nil {
+		t.Fatalf("Error creating network seq6. Err: %v", err)
+	}
+
+	altaIf, err = CreateAltaEndpoint("alta2222", "seq6", 0)
+	if err != nil {
+		t.Fatalf("Error creating alta endpoint. Err: %v", err)
+	}
+	if (altaIf.IntfIpv6Addr != "fd00:20::1") || (altaIf.IntfIpv6Masklen != 112) ||
+		(altaIf.Ipv6Gateway != "fd00:20::ffff") {
+		t.Errorf("Unexpected alta endpoint %+v", altaIf)
+	}
+
+	// Address is released with the end point
+	err = DeleteAltaEndpoint("alta2222", "seq6")
+	if err != nil {
+		t.Errorf("Error deleting alta endpoint. Err: %v", err)
+	}
+	provider := rsrcMgr.FindResourceProvider("subnetAddr6", network.Name)
+	if (provider == nil) || (provider.UsedRsrc != 0) {
+		t.Errorf("Unexpected IPv6 address provider %+v", provider)
+	}
+
+	// Overlapping prefixes are rejected
+	_, err = NewNetwork("overlap6", "", ipam.PoolSpec{Subnet: "172.16.3.0/24"},
+		ipam.PoolSpec{Subnet: "fd00:10::/48"})
+	if err == nil {
+		t.Errorf("Overlapping IPv6 network was created")
+	}
+}
+
+// Test network deletion releases its resources
+func TestDeleteNetwork(t *testing.T) {
+	initNetCtrl()
+
+	// Dual stack network releases IPv6 addresses too
+	DatapathIPv6 = true
+	defer func() { DatapathIPv6 = false }()
+
+	network, err := NewNetwork("deltest", "", ipam.PoolSpec{Subnet: "172.17.1.0/24"},
+		ipam.PoolSpec{Subnet: "fd00:30::/120"})
+	if err != nil {
+		t.Fatalf("Error creating network deltest. Err: %v", err)
+	}
+	_, err = network.NewEndPoint("alta3333.0")
+	if err != nil {
+		t.Fatalf("Error creating network endpoint. Err: %v", err)
+	}
+
+	// Network in use can not be deleted
+	if err = DeleteNetwork("deltest"); err == nil {
+		t.Errorf("Deleted network with end points")
+	}
+	if err = DeleteNetwork("default"); err == nil {
+		t.Errorf("Deleted default network")
+	}
+
+	err = network.DeleteEndPoint("alta3333.0")
+	if err != nil {
+		t.Fatalf("Error deleting end point. Err: %v", err)
+	}
+	if _, ok := rsrcUserIndexes("macaddr", "global")["alta3333.0"]; ok {
+		t.Errorf("Mac address of deleted end point was not released")
+	}
+
+	err = DeleteNetwork("deltest")
+	if err != nil {
+		t.Fatalf("Error deleting network. Err: %v", err)
+	}
+	if _, err := FindNetwork("deltest"); err == nil {
+		t.Errorf("Deleted network was found")
+	}
+	if (rsrcMgr.FindResourceProvider("subnetAddr", "deltest") != nil) ||
+		(rsrcMgr.FindResourceProvider("subnetAddr6", "deltest") != nil) {
+		t.Errorf("Address resources of deleted network were not removed")
+	}
+	if _, ok := rsrcUserIndexes("network", "global")["deltest"]; ok {
+		t.Errorf("Network id of deleted network was not released")
+	}
+
+	// Network can be created again
+	_, err = NewNetwork("deltest", "", ipam.PoolSpec{Subnet: "172.17.1.0/24"}, ipam.PoolSpec{})
+	if err != nil {
+		t.Errorf("Error recreating network deltest. Err: %v", err)
+	}
+
+	// Mac addresses cover the 24 bit space
+	provider := rsrcMgr.FindResourceProvider("macaddr", "global")
+	if (provider == nil) || (provider.NumRsrc != maxMacAddrs) {
+		t.Errorf("Unexpected mac address provider size")
+	}
+}
+
+// Test VLAN and VXLAN encap allocation
+func TestNetworkEncap(t *testing.T) {
+	initNetCtrl()
+
+	defNet, _ := FindNetwork("default")
+	if (defNet.NetSpec.Encap != "vxlan") || (defNet.NetSpec.VlanId != 1) || (defNet.NetSpec.Vni != 1) {
+		t.Errorf("Unexpected default network spec %+v", defNet.NetSpec)
+	}
+
+	vlanNet1, err := NewNetwork("vlan1", "vlan", ipam.PoolSpec{Subnet: "172.18.1.0/24"}, ipam.PoolSpec{})
+	if err != nil {
+		t.Fatalf("Error creating network vlan1. Err: %v", err)
+	}
+	vlanNet2, err := NewNetwork("vlan2", "vlan", ipam.PoolSpec{Subnet: "172.18.2.0/24"}, ipam.PoolSpec{})
+	if err != nil {
+		t.Fatalf("Error creating network vlan2. Err: %v", err)
+	}
+	if (vlanNet1.NetSpec.VlanId != VlanRangeStart) || (vlanNet1.NetSpec.Vni != 0) ||
+		(vlanNet2.NetSpec.VlanId != VlanRangeStart+1) {
+		t.Errorf("Unexpected VLAN network specs %+v, %+v", vlanNet1.NetSpec, vlanNet2.NetSpec)
+	}
+
+	vxlanNet, err := NewNetwork("vxlan1", "vxlan", ipam.PoolSpec{Subnet: "172.18.3.0/24"}, ipam.PoolSpec{})
+	if err != nil {
+		t.Fatalf("Error creating network vxlan1. Err: %v", err)
+	}
+	if (vxlanNet.NetSpec.VlanId != 0) || (vxlanNet.NetSpec.Vni < firstVni) {
+		t.Errorf("Unexpected VXLAN network spec %+v", vxlanNet.NetSpec)
+	}
+
+	// Unknown encap is rejected
+	_, err = NewNetwork("bad", "gre", ipam.PoolSpec{Subnet: "172.18.4.0/24"}, ipam.PoolSpec{})
+	if err == nil {
+		t.Errorf("Network with unknown encap was created")
+	}
+
+	// VLAN id is released on delete
+	err = DeleteNetwork("vlan1")
+	if err != nil {
+		t.Fatalf("Error deleting network vlan1. Err: %v", err)
+	}
+	vlanNet3, err := NewNetwork("vlan3", "vlan", ipam.PoolSpec{Subnet: "172.18.5.0/24"}, ipam.PoolSpec{})
+	if err != nil {
+		t.Fatalf("Error creating network vlan3. Err: %v", err)
+	}
+	if vlanNet3.NetSpec.VlanId != VlanRangeStart {
+		t.Errorf("VLAN id %d was not reused", VlanRangeStart)
+	}
+}
+
+func TestParseVlanRange(t *testing.T) {
+	start, end, err := parseVlanRange("200 - 299")
+	if (err != nil) || (start != 200) || (end != 299) {
+		t.Errorf("Error parsing VLAN range. Got %d-%d. Err: %v", start, end, err)
+	}
+
+	for _, rangeStr := range []string{"200", "1-100", "100-4095", "300-200", "a-b"} {
+		if _, _, err := parseVlanRange(rangeStr); err == nil {
+			t.Errorf("VLAN range %s was accepted", rangeStr)
+		}
+	}
+}
+
+// Create and delete alta endpoints while networks are created and deleted
+func TestConcurrentEndpoints(t *testing.T) {
+	initNetCtrl()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		altaId := "altaConc" + strconv.Itoa(i)
+		go func() {
+			defer wg.Done()
+			_, err := CreateAltaEndpoint(altaId, "concNet", 0)
+			if err != nil {
+				t.Errorf("Error creating endpoint for %s. Err: %v", altaId, err)
+				return
+			}
+			err = DeleteAltaEndpoint(altaId, "concNet")
+			if err != nil {
+				t.Errorf("Error deleting endpoint for %s. Err: %v", altaId, err)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			ListNetwork("")
+			DeleteNetwork("concNet")
+		}()
+	}
+	wg.Wait()
 }
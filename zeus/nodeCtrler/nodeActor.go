@@ -240,6 +240,18 @@ func (self *Node) nodeReachableEvent() error {
 	return nil
 }
 
+// Return the url for a path on the node
+func (self *Node) NodeUrl(path string) (string, error) {
+	// Make sure node is up and running
+	if (self.Fsm.FsmState != "alive") && (self.Fsm.FsmState != "created") &&
+		(self.Fsm.FsmState != "reachable") {
+		log.Errorf("Node %s is down. cant make REST call %s", self.HostAddr, path)
+		return "", errors.New("Node unreachable")
+	}
+
+	return "http://" + self.HostAddr + ":" + strconv.Itoa(self.Port) + path, nil
+}
+
 // Get JSON output from a http request
 func (self *Node) NodeGetReq(path string, data interface{}) error {
 	url := "http://" + self.HostAddr + ":" + strconv.Itoa(self.Port) + path
@@ -337,6 +349,58 @@ func (self *Node) NodePostReq(path string, req interface{}, resp interface{}) er
 	return nil
 }
 
+// perform http DELETE request and return the response
+func (self *Node) NodeDeleteReq(path string, resp interface{}) error {
+	url := "http://" + self.HostAddr + ":" + strconv.Itoa(self.Port) + path
+
+	// Make sure node is up and running
+	if (self.Fsm.FsmState != "alive") && (self.Fsm.FsmState != "created") &&
+		(self.Fsm.FsmState != "reachable") {
+		log.Errorf("Node %s is down. cant make REST call %s", self.HostAddr, path)
+		return errors.New("Node unreachable")
+	}
+
+	log.Infof("Making REST request to url: %s", url)
+
+	// Build the DELETE request
+	req, err := http.NewRequest("DELETE", url, nil)
+	if err != nil {
+		log.Errorf("Error creating http delete request. Err: %v", err)
+		return err
+	}
+
+	// Perform HTTP DELETE operation
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		log.Errorf("Error during http delete. Err: %v", err)
+		return err
+	}
+
+	// Check the response code
+	if res.StatusCode != http.StatusOK {
+		log.Errorf("HTTP error response. Status: %s, StatusCode: %d", res.Status, res.StatusCode)
+		return errors.New("HTTP Error response")
+	}
+
+	// Read the entire response
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		log.Errorf("Error during ioutil readall. Err: %v", err)
+		return err
+	}
+
+	// Convert response json to struct
+	err = json.Unmarshal(body, resp)
+	if err != nil {
+		log.Errorf("Error during json unmarshall. Err: %v", err)
+		return err
+	}
+
+	log.Infof("Results for (%s): %+v\n", url, resp)
+
+	return nil
+}
+
 // FIXME: deprecated. not needed anymore
 // Push network info to node
 func (self *Node) PushNetwork(netSpec altaspec.AltaNetSpec) error {
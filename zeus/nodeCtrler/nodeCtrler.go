@@ -65,32 +65,6 @@ func ListAliveNodes() []*Node {
 	return nodeList
 }
 
-// FilterNodes returns a list of nodes that match a filter criteria
-func FilterNodes(filters map[string]string) []string {
-	var nodeList []string
-
-	// Walk all nodes
-	for _, node := range nodeCtrl.nodeDb {
-		// Make sure node is alive
-		if node.Fsm.FsmState == "alive" {
-			match := true
-			// apply all Filters
-			for key, value := range filters {
-				if node.Attributes[key] == "" {
-					match = false
-				} else if node.Attributes[key] != value {
-					match = false
-				}
-			}
-			if match {
-				nodeList = append(nodeList, node.HostAddr)
-			}
-		}
-	}
-
-	return nodeList
-}
-
 // Main loop of node manager
 func (self *NodeCtrler) nodeMgrLoop() {
 	// Create channels for watch thread
@@ -218,6 +192,21 @@ func NetSpecBcast(netSpec altaspec.AltaNetSpec) error {
 	return nil
 }
 
+// Remove a network from all nodes
+func NetworkDeleteBcast(netName string) error {
+	// Inform all nodes
+	for _, node := range nodeCtrl.nodeDb {
+		var resp altaspec.ReqSuccess
+		err := node.NodeDeleteReq("/network/"+netName, &resp)
+		if err != nil {
+			log.Errorf("Error deleting network %s on node %s. Err: %v",
+				netName, node.HostAddr, err)
+		}
+	}
+
+	return nil
+}
+
 // Perform Get request on a node
 func NodeGetReq(nodeAddr string, path string, data interface{}) error {
 	// Make sure noe exists
@@ -245,3 +234,30 @@ func NodePostReq(nodeAddr string, path string, req interface{}, resp interface{}
 	// Perform POST operation
 	return node.NodePostReq(path, req, resp)
 }
+
+// Perform DELETE request on a node
+func NodeDeleteReq(nodeAddr string, path string, resp interface{}) error {
+	// Make sure noe exists
+	if nodeCtrl.nodeDb[nodeAddr] == nil {
+		log.Errorf("Node %s not found", nodeAddr)
+		return errors.New("Node not found")
+	}
+
+	node := nodeCtrl.nodeDb[nodeAddr]
+
+	// Perform DELETE operation
+	return node.NodeDeleteReq(path, resp)
+}
+
+// Return the url for a path on a node
+func NodeUrl(nodeAddr string, path string) (string, error) {
+	// Make sure noe exists
+	if nodeCtrl.nodeDb[nodeAddr] == nil {
+		log.Errorf("Node %s not found", nodeAddr)
+		return "", errors.New("Node not found")
+	}
+
+	node := nodeCtrl.nodeDb[nodeAddr]
+
+	return node.NodeUrl(path)
+}
@@ -0,0 +1,398 @@
+package rebalancer
+
+// This file implements the rebalancer. It periodically compares resource
+// usage of the nodes and migrates altas from busy nodes to idle ones
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/contiv/symphony/pkg/altaspec"
+	"github.com/contiv/symphony/pkg/rsrcMgr"
+	"github.com/contiv/symphony/zeus/common"
+	"github.com/contiv/symphony/zeus/nodeCtrler"
+	"github.com/contiv/symphony/zeus/scheduler"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// How often the imbalance is checked
+const rebalanceInterval = time.Minute
+
+// Rebalance when utilization of busiest and idlest nodes differ by more than this
+var ImbalanceThreshold = 0.3
+
+// Max number of altas migrated in a minute
+var MaxMigrationsPerMin = 2
+
+// Resource types that count towards node utilization
+var utilRsrcTypes = []string{"cpu", "memory"}
+
+// Resource usage of a node
+type nodeUsage struct {
+	total map[string]float64 // Total resources by type
+	used  map[string]float64 // Used resources by type
+}
+
+// Utilization of the most used resource type, from 0 to 1
+func (self *nodeUsage) util() float64 {
+	var util float64
+	for _, rsrcType := range utilRsrcTypes {
+		if self.total[rsrcType] > 0 {
+			rsrcUtil := self.used[rsrcType] / self.total[rsrcType]
+			if rsrcUtil > util {
+				util = rsrcUtil
+			}
+		}
+	}
+
+	return util
+}
+
+// Picks the node an alta would be migrated to, given the moves planned before it
+type targetFunc func(alta *common.AltaState, planned []scheduler.PlannedMove) (string, error)
+
+// Rebalancer state
+type Rebalancer struct {
+	altaCtrler     common.AltaCtrlInterface // Alta controller to migrate altas
+	mutex          sync.Mutex               // Lock for migration times
+	migrationTimes []time.Time              // Recent migrations for rate limiting
+	stopChan       chan struct{}            // Channel to stop the run loop
+}
+
+// Create a new rebalancer and start it
+func NewRebalancer(altaCtrler common.AltaCtrlInterface) *Rebalancer {
+	rebalancer := new(Rebalancer)
+
+	rebalancer.altaCtrler = altaCtrler
+	rebalancer.stopChan = make(chan struct{})
+
+	// Kick off the run loop
+	go rebalancer.runLoop()
+
+	return rebalancer
+}
+
+// Stop rebalancing. Called when we lose mastership
+func (self *Rebalancer) Stop() {
+	close(self.stopChan)
+}
+
+// Periodically rebalance the cluster
+func (self *Rebalancer) runLoop() {
+	ticker := time.NewTicker(rebalanceInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			self.rebalance()
+		case <-self.stopChan:
+			return
+		}
+	}
+}
+
+// Return the moves rebalancer would make right now, without making them
+func (self *Rebalancer) RebalanceReport() *common.RebalanceReport {
+	return self.plan(MaxMigrationsPerMin)
+}
+
+// Migrate altas from busy nodes within the rate limit
+func (self *Rebalancer) rebalance() {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	// Forget migrations older than a minute
+	now := time.Now()
+	var recent []time.Time
+	for _, migrateTime := range self.migrationTimes {
+		if now.Sub(migrateTime) < time.Minute {
+			recent = append(recent, migrateTime)
+		}
+	}
+	self.migrationTimes = recent
+
+	budget := MaxMigrationsPerMin - len(self.migrationTimes)
+	if budget <= 0 {
+		return
+	}
+
+	report := self.plan(budget)
+	for _, move := range report.Moves {
+		log.Infof("Rebalancing: moving alta %s from node %s to %s. Imbalance: %.2f", move.AltaId,
+			move.FromNode, move.ToNode, report.Imbalance)
+
+		// Pin the alta to the planned node so that moves land where we planned
+		err := self.altaCtrler.MigrateAlta(move.AltaId, move.ToNode)
+		if err != nil {
+			log.Errorf("Error migrating alta %s. Err: %v", move.AltaId, err)
+			continue
+		}
+
+		self.migrationTimes = append(self.migrationTimes, now)
+	}
+}
+
+// Plan the moves from current resource usage
+func (self *Rebalancer) plan(maxMoves int) *common.RebalanceReport {
+	snapshot := rsrcMgr.GetSnapshot()
+
+	// Usage of the alive nodes
+	usage := make(map[string]*nodeUsage)
+	for _, node := range nodeCtrler.ListAliveNodes() {
+		nodeUse := nodeUsage{
+			total: make(map[string]float64),
+			used:  make(map[string]float64),
+		}
+		for _, rsrcType := range utilRsrcTypes {
+			if provider := snapshot[rsrcType][node.HostAddr]; provider != nil {
+				nodeUse.total[rsrcType] = provider.NumRsrc
+				nodeUse.used[rsrcType] = provider.UsedRsrc
+			}
+		}
+		usage[node.HostAddr] = &nodeUse
+	}
+
+	// Alta controller returns copies of alta state that we can read freely
+	return planMoves(usage, self.altaCtrler.ListAlta(), maxMoves,
+		func(alta *common.AltaState, planned []scheduler.PlannedMove) (string, error) {
+			return scheduler.PlanMigration(&alta.Spec, alta.CurrNode, planned)
+		})
+}
+
+// Pick altas to move from the busiest node till the cluster is balanced or
+// we run out of moves. Each move must reduce the imbalance
+func planMoves(usage map[string]*nodeUsage, altas []*common.AltaState, maxMoves int,
+	planTarget targetFunc) *common.RebalanceReport {
+	report := common.RebalanceReport{
+		Time:      time.Now(),
+		Threshold: ImbalanceThreshold,
+		NodeUsage: make(map[string]float64),
+	}
+	for nodeAddr, nodeUse := range usage {
+		report.NodeUsage[nodeAddr] = nodeUse.util()
+	}
+	report.Imbalance, _, _ = imbalance(usage)
+
+	// Count the instances of each service that are already disrupted
+	disrupted := make(map[string]int)
+	for _, alta := range altas {
+		if !isAvailable(alta) {
+			disrupted[serviceKey(alta)]++
+		}
+	}
+
+	skipped := make(map[string]bool)
+	moved := make(map[string]bool)
+	var planned []scheduler.PlannedMove
+	for len(report.Moves) < maxMoves {
+		imbal, busiest, _ := imbalance(usage)
+		if imbal <= ImbalanceThreshold {
+			break
+		}
+
+		// Try the altas on busiest node, biggest first
+		var candidates []*common.AltaState
+		for _, alta := range altas {
+			if (alta.CurrNode == busiest) && !moved[alta.Spec.AltaId] {
+				candidates = append(candidates, alta)
+			}
+		}
+		sort.Sort(bySize(candidates))
+
+		var move *common.RebalanceMove
+		for _, alta := range candidates {
+			// Check if we can move it
+			reason := notMovableReason(alta, disrupted)
+			var target string
+			if reason == "" {
+				var err error
+				target, err = planTarget(alta, planned)
+				if err != nil {
+					reason = "no other node can run it: " + err.Error()
+				} else if usage[target] == nil {
+					reason = "target node " + target + " is not alive"
+				} else if !reducesImbalance(usage, alta, busiest, target) {
+					reason = "moving it would not reduce the imbalance"
+				}
+			}
+			if reason != "" {
+				if !skipped[alta.Spec.AltaId] {
+					skipped[alta.Spec.AltaId] = true
+					report.Skipped = append(report.Skipped, common.RebalanceSkip{
+						AltaId:   alta.Spec.AltaId,
+						AltaName: alta.Spec.AltaName,
+						NodeAddr: alta.CurrNode,
+						Reason:   reason,
+					})
+				}
+				continue
+			}
+
+			move = &common.RebalanceMove{
+				AltaId:   alta.Spec.AltaId,
+				AltaName: alta.Spec.AltaName,
+				FromNode: busiest,
+				ToNode:   target,
+			}
+			break
+		}
+
+		// Nothing on busiest node can be moved
+		if move == nil {
+			break
+		}
+
+		// Account for the move and look again
+		alta := altaById(altas, move.AltaId)
+		moveUsage(usage, alta, move.FromNode, move.ToNode)
+		moved[move.AltaId] = true
+		disrupted[serviceKey(alta)]++
+		report.Moves = append(report.Moves, *move)
+		planned = append(planned, scheduler.PlannedMove{
+			Spec:     &alta.Spec,
+			FromNode: move.FromNode,
+			ToNode:   move.ToNode,
+		})
+	}
+
+	return &report
+}
+
+// Return the imbalance, busiest node and idlest node
+func imbalance(usage map[string]*nodeUsage) (float64, string, string) {
+	var busiest, idlest string
+	for nodeAddr, nodeUse := range usage {
+		util := nodeUse.util()
+		if (busiest == "") || (util > usage[busiest].util()) ||
+			((util == usage[busiest].util()) && (nodeAddr < busiest)) {
+			busiest = nodeAddr
+		}
+		if (idlest == "") || (util < usage[idlest].util()) ||
+			((util == usage[idlest].util()) && (nodeAddr < idlest)) {
+			idlest = nodeAddr
+		}
+	}
+
+	if busiest == "" {
+		return 0, "", ""
+	}
+
+	return usage[busiest].util() - usage[idlest].util(), busiest, idlest
+}
+
+// Check if the alta can be moved. Returns why it cant be moved
+func notMovableReason(alta *common.AltaState, disrupted map[string]int) string {
+	if alta.Spec.Kind == "job" {
+		return "jobs are not moved"
+	}
+	if alta.FsmState != "running" {
+		return "alta is not running"
+	}
+
+	// Node local volumes cant move with the alta
+	for _, volume := range alta.Spec.Volumes {
+		if volume.DatastoreType == "HostVolume" {
+			return "alta has node local volume " + volume.DatastoreVolumeId
+		}
+	}
+
+	// Check the disruption budget of the service
+	budget := alta.Spec.SchedPolicy.MaxUnavailable
+	if budget == 0 {
+		budget = 1
+	}
+	if disrupted[serviceKey(alta)] >= budget {
+		return "service disruption budget is used up"
+	}
+
+	return ""
+}
+
+// Check if target node stays less utilized than the busy node was.
+// Busy node only gets less utilized by the move
+func reducesImbalance(usage map[string]*nodeUsage, alta *common.AltaState, from, to string) bool {
+	toUse := copyUsage(usage[to])
+	for rsrcType, numRsrc := range altaResources(alta) {
+		toUse.used[rsrcType] += numRsrc
+	}
+
+	return toUse.util() < usage[from].util()
+}
+
+// Update the usage to reflect a move
+func moveUsage(usage map[string]*nodeUsage, alta *common.AltaState, from, to string) {
+	for rsrcType, numRsrc := range altaResources(alta) {
+		usage[from].used[rsrcType] -= numRsrc
+		usage[to].used[rsrcType] += numRsrc
+	}
+}
+
+// Copy the usage of a node
+func copyUsage(nodeUse *nodeUsage) *nodeUsage {
+	newUse := nodeUsage{
+		total: nodeUse.total,
+		used:  make(map[string]float64),
+	}
+	for rsrcType, numRsrc := range nodeUse.used {
+		newUse.used[rsrcType] = numRsrc
+	}
+
+	return &newUse
+}
+
+// Resources of an alta that count towards utilization
+func altaResources(alta *common.AltaState) map[string]float64 {
+	return map[string]float64{
+		"cpu":    float64(alta.Spec.NumCpu),
+		"memory": float64(alta.Spec.Memory),
+	}
+}
+
+// Alta is running and ready to serve
+func isAvailable(alta *common.AltaState) bool {
+	if alta.FsmState != "running" {
+		return false
+	}
+
+	return (alta.Spec.ReadinessProbe == nil) || alta.Ready
+}
+
+// Key of the service alta belongs to. Altas without a service are their own service
+func serviceKey(alta *common.AltaState) string {
+	labels := alta.Spec.Labels
+	if labels[altaspec.ServiceLabel] == "" {
+		return "alta:" + alta.Spec.AltaId
+	}
+
+	return labels[altaspec.TenantLabel] + ":" + labels[altaspec.AppLabel] + ":" +
+		labels[altaspec.ServiceLabel]
+}
+
+// Find an alta in the list
+func altaById(altas []*common.AltaState, altaId string) *common.AltaState {
+	for _, alta := range altas {
+		if alta.Spec.AltaId == altaId {
+			return alta
+		}
+	}
+
+	return nil
+}
+
+// Sort altas by descending cpu, memory and ascending alta id
+type bySize []*common.AltaState
+
+func (s bySize) Len() int      { return len(s) }
+func (s bySize) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s bySize) Less(i, j int) bool {
+	if s[i].Spec.NumCpu != s[j].Spec.NumCpu {
+		return s[i].Spec.NumCpu > s[j].Spec.NumCpu
+	}
+	if s[i].Spec.Memory != s[j].Spec.Memory {
+		return s[i].Spec.Memory > s[j].Spec.Memory
+	}
+	return s[i].Spec.AltaId < s[j].Spec.AltaId
+}
@@ -0,0 +1,122 @@
+package rebalancer
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/contiv/symphony/pkg/altaspec"
+	"github.com/contiv/symphony/zeus/common"
+	"github.com/contiv/symphony/zeus/scheduler"
+)
+
+// Build node usage with 8 cpus and 8G memory
+func testUsage(usedCpu float64) *nodeUsage {
+	return &nodeUsage{
+		total: map[string]float64{"cpu": 8, "memory": 8192},
+		used:  map[string]float64{"cpu": usedCpu, "memory": 0},
+	}
+}
+
+// Build a running alta of a service
+func testAlta(altaId, nodeAddr, service string, numCpu uint32) *common.AltaState {
+	alta := common.AltaState{
+		CurrNode: nodeAddr,
+		FsmState: "running",
+	}
+	alta.Spec.AltaId = altaId
+	alta.Spec.NumCpu = numCpu
+	alta.Spec.Labels = map[string]string{altaspec.ServiceLabel: service}
+
+	return &alta
+}
+
+// Always move to the idle node
+func moveToIdle(alta *common.AltaState, planned []scheduler.PlannedMove) (string, error) {
+	return "10.1.1.2", nil
+}
+
+// Test moves are planned from busy node to idle one
+func TestPlanMoves(t *testing.T) {
+	usage := map[string]*nodeUsage{
+		"10.1.1.1": testUsage(8),
+		"10.1.1.2": testUsage(0),
+	}
+	altas := []*common.AltaState{
+		testAlta("web1", "10.1.1.1", "web", 2),
+		testAlta("web2", "10.1.1.1", "web", 2),
+		testAlta("db1", "10.1.1.1", "db", 1),
+	}
+
+	// One web instance moves first. Disruption budget keeps the other web
+	// instance in place, so db moves next and leaves the cluster balanced
+	report := planMoves(usage, altas, 5, moveToIdle)
+	if report.Imbalance != 1 {
+		t.Errorf("Unexpected imbalance %v", report.Imbalance)
+	}
+	if (len(report.Moves) != 2) || (report.Moves[0].AltaId != "web1") || (report.Moves[1].AltaId != "db1") ||
+		(report.Moves[0].ToNode != "10.1.1.2") {
+		t.Errorf("Unexpected moves: %+v", report.Moves)
+	}
+	if (len(report.Skipped) != 1) || (report.Skipped[0].AltaId != "web2") {
+		t.Errorf("Unexpected skipped altas: %+v", report.Skipped)
+	}
+
+	// Each target sees the moves planned before it
+	usage["10.1.1.1"], usage["10.1.1.2"] = testUsage(8), testUsage(0)
+	var seen [][]scheduler.PlannedMove
+	planMoves(usage, altas, 5, func(alta *common.AltaState, planned []scheduler.PlannedMove) (string, error) {
+		seen = append(seen, planned)
+		return "10.1.1.2", nil
+	})
+	if (len(seen) != 2) || (len(seen[0]) != 0) || (len(seen[1]) != 1) || (seen[1][0].Spec.AltaId != "web1") ||
+		(seen[1][0].ToNode != "10.1.1.2") {
+		t.Errorf("Unexpected planned moves: %+v", seen)
+	}
+
+	// Rate limit caps the number of moves
+	usage["10.1.1.1"], usage["10.1.1.2"] = testUsage(8), testUsage(0)
+	report = planMoves(usage, altas, 1, moveToIdle)
+	if len(report.Moves) != 1 {
+		t.Errorf("Unexpected moves: %+v", report.Moves)
+	}
+}
+
+// Test altas that cant be moved are skipped
+func TestPlanMovesSkip(t *testing.T) {
+	usage := map[string]*nodeUsage{
+		"10.1.1.1": testUsage(6),
+		"10.1.1.2": testUsage(0),
+	}
+
+	local := testAlta("local", "10.1.1.1", "local", 2)
+	local.Spec.Volumes = []altaspec.AltaVolumeBind{{DatastoreType: "HostVolume", DatastoreVolumeId: "data"}}
+
+	// Another instance of the service is already down
+	web1 := testAlta("web1", "10.1.1.1", "web", 2)
+	web2 := testAlta("web2", "10.1.1.3", "web", 2)
+	web2.FsmState = "rescheduling"
+
+	job := testAlta("job", "10.1.1.1", "", 2)
+	job.Spec.Kind = "job"
+
+	report := planMoves(usage, []*common.AltaState{local, web1, web2, job}, 5, moveToIdle)
+	if len(report.Moves) != 0 || len(report.Skipped) != 3 {
+		t.Errorf("Unexpected report: %+v", report)
+	}
+
+	// Nothing moves when no other node fits
+	report = planMoves(usage, []*common.AltaState{testAlta("web3", "10.1.1.1", "web3", 2)}, 5,
+		func(alta *common.AltaState, planned []scheduler.PlannedMove) (string, error) {
+			return "", errors.New("No nodes that match the filter")
+		})
+	if len(report.Moves) != 0 || len(report.Skipped) != 1 {
+		t.Errorf("Unexpected report: %+v", report)
+	}
+
+	// Balanced cluster is left alone
+	usage["10.1.1.2"] = testUsage(5)
+	report = planMoves(usage, []*common.AltaState{testAlta("web4", "10.1.1.1", "web4", 2)}, 5, moveToIdle)
+	if len(report.Moves) != 0 {
+		t.Errorf("Balanced cluster was rebalanced: %+v", report.Moves)
+	}
+}
@@ -0,0 +1,248 @@
+/***
+Copyright 2014 Cisco Systems Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Constraint on a node attribute. Supported expressions are
+//
+//	key == value, key != value
+//	key in (v1, v2), key notin (v1, v2)
+//	key exists, key !exists
+//	key =~ regex
+//	key < number, key > number
+type Constraint struct {
+	Key    string   // Attribute name
+	Op     string   // Operator
+	Values []string // Values to compare against
+
+	regex  *regexp.Regexp // Compiled regex for =~
+	number float64        // Parsed number for < and >
+}
+
+// Operators that take a single value, longest first
+var constraintValueOps = []string{"==", "!=", "=~", "<", ">"}
+
+// Parse a constraint expression
+func ParseConstraint(expr string) (*Constraint, error) {
+	expr = strings.TrimSpace(expr)
+
+	// Key runs till whitespace or an operator character
+	keyEnd := strings.IndexAny(expr, " \t=!<>(")
+	if keyEnd < 0 {
+		return nil, fmt.Errorf("Invalid constraint %q: missing operator", expr)
+	}
+	if keyEnd == 0 {
+		return nil, fmt.Errorf("Invalid constraint %q: missing attribute name", expr)
+	}
+
+	constraint := Constraint{Key: expr[:keyEnd]}
+	rest := strings.TrimSpace(expr[keyEnd:])
+
+	switch {
+	case rest == "exists":
+		constraint.Op = "exists"
+		return &constraint, nil
+	case rest == "!exists":
+		constraint.Op = "!exists"
+		return &constraint, nil
+	case strings.HasPrefix(rest, "notin"):
+		constraint.Op = "notin"
+		rest = rest[len("notin"):]
+	case strings.HasPrefix(rest, "in"):
+		constraint.Op = "in"
+		rest = rest[len("in"):]
+	default:
+		for _, op := range constraintValueOps {
+			if strings.HasPrefix(rest, op) {
+				constraint.Op = op
+				break
+			}
+		}
+		if constraint.Op == "" {
+			return nil, fmt.Errorf("Invalid constraint %q: unknown operator", expr)
+		}
+
+		value := strings.TrimSpace(rest[len(constraint.Op):])
+		if value == "" {
+			return nil, fmt.Errorf("Invalid constraint %q: missing value", expr)
+		}
+		constraint.Values = []string{value}
+
+		// Parse the value based on operator
+		switch constraint.Op {
+		case "=~":
+			regex, err := regexp.Compile(value)
+			if err != nil {
+				return nil, fmt.Errorf("Invalid constraint %q: bad regex: %v", expr, err)
+			}
+			constraint.regex = regex
+		case "<", ">":
+			number, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return nil, fmt.Errorf("Invalid constraint %q: %s is not a number", expr, value)
+			}
+			constraint.number = number
+		}
+
+		return &constraint, nil
+	}
+
+	// Parse the value list for in and notin
+	rest = strings.TrimSpace(rest)
+	if !strings.HasPrefix(rest, "(") || !strings.HasSuffix(rest, ")") {
+		return nil, fmt.Errorf("Invalid constraint %q: expected value list in parentheses", expr)
+	}
+	for _, value := range strings.Split(rest[1:len(rest)-1], ",") {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			return nil, fmt.Errorf("Invalid constraint %q: empty value in list", expr)
+		}
+		constraint.Values = append(constraint.Values, value)
+	}
+
+	return &constraint, nil
+}
+
+// Parse a filter from scheduler policy. Value is either an expression
+// without the key, e.g. "in (a, b)", or a plain value to match exactly
+func ParseFilter(key, value string) (*Constraint, error) {
+	value = strings.TrimSpace(value)
+
+	if isConstraintExpr(value) {
+		return ParseConstraint(key + " " + value)
+	}
+
+	if key == "" {
+		return nil, fmt.Errorf("Invalid filter %q: missing attribute name", value)
+	}
+
+	return &Constraint{Key: key, Op: "==", Values: []string{value}}, nil
+}
+
+// Check if a filter value starts with an operator
+func isConstraintExpr(value string) bool {
+	if (value == "exists") || (value == "!exists") {
+		return true
+	}
+	for _, prefix := range []string{"in ", "in(", "notin ", "notin("} {
+		if strings.HasPrefix(value, prefix) {
+			return true
+		}
+	}
+	for _, op := range constraintValueOps {
+		if strings.HasPrefix(value, op) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Parse all filters and constraint expressions of a scheduler policy
+func ParseConstraints(filters map[string]string, exprs []string) ([]*Constraint, error) {
+	var constraints []*Constraint
+
+	// Parse the filters in a predictable order
+	var keys []string
+	for key := range filters {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		constraint, err := ParseFilter(key, filters[key])
+		if err != nil {
+			return nil, err
+		}
+		constraints = append(constraints, constraint)
+	}
+
+	for _, expr := range exprs {
+		constraint, err := ParseConstraint(expr)
+		if err != nil {
+			return nil, err
+		}
+		constraints = append(constraints, constraint)
+	}
+
+	return constraints, nil
+}
+
+// Check if node attributes satisfy the constraint
+func (self *Constraint) Match(attributes map[string]string) bool {
+	value, exists := attributes[self.Key]
+
+	switch self.Op {
+	case "exists":
+		return exists
+	case "!exists":
+		return !exists
+	case "==":
+		return exists && (value == self.Values[0])
+	case "!=":
+		return !exists || (value != self.Values[0])
+	case "in":
+		return exists && self.hasValue(value)
+	case "notin":
+		return !exists || !self.hasValue(value)
+	case "=~":
+		return exists && self.regex.MatchString(value)
+	case "<", ">":
+		if !exists {
+			return false
+		}
+		number, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil {
+			return false
+		}
+		if self.Op == "<" {
+			return number < self.number
+		}
+		return number > self.number
+	}
+
+	return false
+}
+
+// Check if value is in the value list
+func (self *Constraint) hasValue(value string) bool {
+	for _, v := range self.Values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Return the constraint as an expression
+func (self *Constraint) String() string {
+	switch self.Op {
+	case "exists", "!exists":
+		return self.Key + " " + self.Op
+	case "in", "notin":
+		return self.Key + " " + self.Op + " (" + strings.Join(self.Values, ", ") + ")"
+	default:
+		return self.Key + " " + self.Op + " " + self.Values[0]
+	}
+}
@@ -0,0 +1,151 @@
+package scheduler
+
+import (
+	"testing"
+
+	"github.com/contiv/symphony/pkg/altaspec"
+)
+
+// Test parsing constraint expressions
+func TestParseConstraint(t *testing.T) {
+	validExprs := map[string]string{
+		"zone == a":            "zone == a",
+		"zone!=a":              "zone != a",
+		"zone in (a, b,c)":     "zone in (a, b, c)",
+		"zone notin(a)":        "zone notin (a)",
+		"gpu exists":           "gpu exists",
+		"ssd !exists":          "ssd !exists",
+		"host =~ ^rack[0-9]+$": "host =~ ^rack[0-9]+$",
+		"cpu-mhz > 2000":       "cpu-mhz > 2000",
+		"cpu-mhz<3000.5":       "cpu-mhz < 3000.5",
+	}
+	for expr, str := range validExprs {
+		constraint, err := ParseConstraint(expr)
+		if err != nil {
+			t.Errorf("Error parsing valid constraint %q. Err: %v", expr, err)
+			continue
+		}
+		if constraint.String() != str {
+			t.Errorf("Constraint %q parsed as %q, expected %q", expr, constraint.String(), str)
+		}
+	}
+
+	invalidExprs := []string{
+		"",
+		"zone",
+		"== a",
+		"zone ==",
+		"zone in a, b",
+		"zone in (a,,b)",
+		"zone like a",
+		"host =~ [a-",
+		"cpu-mhz > fast",
+	}
+	for _, expr := range invalidExprs {
+		_, err := ParseConstraint(expr)
+		if err == nil {
+			t.Errorf("Invalid constraint %q parsed without error", expr)
+		}
+	}
+}
+
+// Test matching constraints against node attributes
+func TestConstraintMatch(t *testing.T) {
+	attr := map[string]string{
+		"zone":    "a",
+		"host":    "rack12",
+		"cpu-mhz": "2400",
+		"gpu":     "",
+	}
+
+	testCases := map[string]bool{
+		"zone == a":            true,
+		"zone == b":            false,
+		"zone != b":            true,
+		"rack != b":            true,
+		"zone in (a, b)":       true,
+		"zone in (b, c)":       false,
+		"rack in (a)":          false,
+		"zone notin (b)":       true,
+		"zone notin (a)":       false,
+		"gpu exists":           true,
+		"ssd exists":           false,
+		"ssd !exists":          true,
+		"host =~ ^rack[0-9]+$": true,
+		"zone =~ ^rack":        false,
+		"cpu-mhz > 2000":       true,
+		"cpu-mhz < 2000":       false,
+		"zone > 1":             false,
+		"ram > 1":              false,
+	}
+	for expr, expMatch := range testCases {
+		constraint, err := ParseConstraint(expr)
+		if err != nil {
+			t.Fatalf("Error parsing constraint %q. Err: %v", expr, err)
+		}
+		if constraint.Match(attr) != expMatch {
+			t.Errorf("Constraint %q: expected match %v", expr, expMatch)
+		}
+	}
+}
+
+// Test filters from scheduler policy
+func TestParseFilters(t *testing.T) {
+	filters := map[string]string{
+		"zone":    "a",
+		"cpu-mhz": "> 2000",
+		"rack":    "in (r1, r2)",
+	}
+
+	constraints, err := ParseConstraints(filters, []string{"ssd exists"})
+	if err != nil {
+		t.Fatalf("Error parsing filters. Err: %v", err)
+	}
+
+	expList := []string{"cpu-mhz > 2000", "rack in (r1, r2)", "zone == a", "ssd exists"}
+	if len(constraints) != len(expList) {
+		t.Fatalf("Expected %d constraints, got %d", len(expList), len(constraints))
+	}
+	for idx, constraint := range constraints {
+		if constraint.String() != expList[idx] {
+			t.Errorf("Expected constraint %q, got %q", expList[idx], constraint.String())
+		}
+	}
+
+	// Invalid filters are an error
+	_, err = ParseConstraints(map[string]string{"zone": "in a"}, nil)
+	if err == nil {
+		t.Errorf("Invalid filter parsed without error")
+	}
+}
+
+// Test scheduling with constraints
+func TestConstraintFilter(t *testing.T) {
+	Init()
+
+	spec := altaspec.AltaSpec{
+		AltaId: "test",
+		NumCpu: 1,
+		Memory: 256,
+	}
+	spec.SchedPolicy.Constraints = []string{"zone in (a, b)", "cpu-mhz > 2000"}
+
+	nodes := []*NodeInfo{
+		testNode("10.1.1.1", 4, 4096, map[string]string{"zone": "a", "cpu-mhz": "1800"}),
+		testNode("10.1.1.2", 4, 4096, map[string]string{"zone": "b", "cpu-mhz": "2400"}),
+		testNode("10.1.1.3", 4, 4096, map[string]string{"zone": "c", "cpu-mhz": "3000"}),
+	}
+
+	nodeList := testRank(t, "leastUsed", &spec, nodes)
+	if len(nodeList) != 1 || nodeList[0] != "10.1.1.2" {
+		t.Errorf("Unexpected constraint filter result: %v", nodeList)
+	}
+
+	// Invalid constraint must be an error
+	spec.SchedPolicy.Constraints = []string{"zone in a"}
+	sched, _ := Scheduler("leastUsed")
+	_, err := sched.RankNodes(&spec, nodes)
+	if err == nil {
+		t.Errorf("Invalid constraint did not return an error")
+	}
+}
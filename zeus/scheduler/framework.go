@@ -0,0 +1,436 @@
+/***
+Copyright 2014 Cisco Systems Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/contiv/symphony/pkg/altaspec"
+	"github.com/contiv/symphony/pkg/rsrcMgr"
+	"github.com/contiv/symphony/zeus/common"
+	"github.com/contiv/symphony/zeus/nodeCtrler"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// ****************** Filter/score scheduling framework *****************
+
+// Node information used by the plugins
+type NodeInfo struct {
+	HostAddr   string             // Node address
+	Attributes map[string]string  // Node attributes
+	Total      map[string]float64 // Total resources on the node, by resource type
+	Free       map[string]float64 // Free resources on the node, by resource type
+	Altas      []AltaPlacement    // Altas already placed on the node
+}
+
+// Placement of an existing alta. Node and resources come from what
+// resource manager allocated to the alta
+type AltaPlacement struct {
+	AltaId   string            // Alta id
+	NodeAddr string            // Node where alta is placed
+	Labels   map[string]string // Labels of the alta
+	JobName  string            // Job the alta belongs to
+
+	Priority    int                // Scheduling priority of the alta
+	Resources   map[string]float64 // Resources held by the alta, by resource type
+	Preemptible bool               // Alta can be evicted in its current state
+}
+
+// Filter plugin decides if an alta can be placed on a node.
+// Returns an error describing why the node was rejected
+type FilterPlugin interface {
+	Name() string
+	Filter(spec *altaspec.AltaSpec, node *NodeInfo, allNodes []*NodeInfo) error
+}
+
+// Score plugin ranks the nodes that passed the filters. Higher is better.
+// Raw scores are normalized across nodes before weights are applied.
+// allNodes includes the nodes that were filtered out
+type ScorePlugin interface {
+	Name() string
+	Score(spec *altaspec.AltaSpec, node *NodeInfo, allNodes []*NodeInfo) float64
+}
+
+// Score plugin with its weight
+type weightedScorer struct {
+	plugin ScorePlugin
+	weight float64
+}
+
+// Error returned when an alta cant be placed. Explains why each node was rejected
+type ScheduleError struct {
+	Message    string                 // Summary of the error
+	Rejections []common.NodeRejection // Why each node was rejected
+}
+
+func (self *ScheduleError) Error() string {
+	var reasons []string
+	for _, rejection := range self.Rejections {
+		reasons = append(reasons, rejection.NodeAddr+": "+rejection.Reason)
+	}
+	if len(reasons) == 0 {
+		return self.Message
+	}
+
+	return self.Message + ". " + strings.Join(reasons, "; ")
+}
+
+// Node with its final score
+type nodeScore struct {
+	node  *NodeInfo
+	score float64
+}
+
+// Scheduler that composes filter and score plugins
+type frameworkSched struct {
+	policyName string           // Name of the policy
+	filters    []FilterPlugin   // Filters to apply
+	scorers    []weightedScorer // Scores to apply
+}
+
+// Return the list of schedulable nodes. Tests can replace this
+var listNodes = listAliveNodes
+
+// Return labels and priorities of all altas. Set by alta controller
+var placementSource func() []AltaPlacement
+
+// Altas we scheduled. Source may have been read before these altas were
+// created, so schedules look them up here too
+var schedAltaMutex sync.Mutex
+var schedAltaDb map[string]AltaPlacement
+
+// Set the function that returns labels and priorities of all altas
+func SetPlacementSource(source func() []AltaPlacement) {
+	placementSource = source
+}
+
+// Remember an alta we are scheduling
+func registerAlta(spec *altaspec.AltaSpec) {
+	schedAltaMutex.Lock()
+	defer schedAltaMutex.Unlock()
+
+	schedAltaDb[spec.AltaId] = AltaPlacement{
+		AltaId:   spec.AltaId,
+		Labels:   spec.Labels,
+		JobName:  spec.JobName,
+		Priority: spec.SchedPolicy.Priority,
+	}
+}
+
+// Find an alta we scheduled
+func findSchedAlta(altaId string) (AltaPlacement, bool) {
+	schedAltaMutex.Lock()
+	defer schedAltaMutex.Unlock()
+
+	placement, ok := schedAltaDb[altaId]
+	return placement, ok
+}
+
+// ForgetAlta is called when an alta is deleted
+func ForgetAlta(altaId string) {
+	schedAltaMutex.Lock()
+	defer schedAltaMutex.Unlock()
+
+	delete(schedAltaDb, altaId)
+}
+
+// Return the known altas by alta id
+func listAltaInfo() map[string]AltaPlacement {
+	altaInfo := make(map[string]AltaPlacement)
+	if placementSource != nil {
+		for _, placement := range placementSource() {
+			altaInfo[placement.AltaId] = placement
+		}
+	}
+
+	return altaInfo
+}
+
+// Build node info for all alive nodes. Resources and altas are filled in
+// from resource manager's snapshot at the time of scheduling
+func listAliveNodes() []*NodeInfo {
+	var nodeList []*NodeInfo
+
+	for _, node := range nodeCtrler.ListAliveNodes() {
+		nodeInfo := NodeInfo{
+			HostAddr:   node.HostAddr,
+			Attributes: node.Attributes,
+			Total:      make(map[string]float64),
+			Free:       make(map[string]float64),
+		}
+
+		nodeList = append(nodeList, &nodeInfo)
+	}
+
+	return nodeList
+}
+
+// Fill in the resources each node provides from a resource snapshot.
+// Altas on each node are the users of its resources
+func fillNodeResources(nodes []*NodeInfo, altaInfo map[string]AltaPlacement, snapshot rsrcMgr.Snapshot) {
+	for _, node := range nodes {
+		node.Total = make(map[string]float64)
+		node.Free = make(map[string]float64)
+		nodeAltas := make(map[string]*AltaPlacement)
+
+		for rsrcType, providers := range snapshot {
+			provider := providers[node.HostAddr]
+			if provider == nil {
+				continue
+			}
+			node.Total[rsrcType] = provider.NumRsrc
+			node.Free[rsrcType] = provider.FreeRsrc
+
+			for userKey, user := range provider.RsrcUsers {
+				placement := nodeAltas[userKey]
+				if placement == nil {
+					info, ok := altaInfo[userKey]
+					if !ok {
+						info, ok = findSchedAlta(userKey)
+					}
+					if !ok {
+						log.Debugf("Unknown user %s of %s on node %s", userKey, rsrcType, node.HostAddr)
+						continue
+					}
+
+					info.NodeAddr = node.HostAddr
+					info.Resources = make(map[string]float64)
+					placement = &info
+					nodeAltas[userKey] = placement
+				}
+				placement.Resources[rsrcType] += user.UsedRsrc
+			}
+		}
+
+		// Keep the altas in a predictable order
+		node.Altas = nil
+		for _, placement := range nodeAltas {
+			node.Altas = append(node.Altas, *placement)
+		}
+		sort.Sort(byAltaId(node.Altas))
+	}
+}
+
+// Sort placements by alta id
+type byAltaId []AltaPlacement
+
+func (s byAltaId) Len() int           { return len(s) }
+func (s byAltaId) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s byAltaId) Less(i, j int) bool { return s[i].AltaId < s[j].AltaId }
+
+// Schedule the alta using this policy
+// 1. Filter out the nodes that can not run the alta
+// 2. Rank the remaining nodes by weighted score
+// 3. Reserve resources on the best node
+// Ranking and reservation happen in one step inside the resource manager
+// so that concurrent schedules can not pick the same free resources and
+// see each other's placements for affinity and spread
+func (self *frameworkSched) ScheduleAlta(spec *altaspec.AltaSpec) (string, error) {
+	var nodeAddr string
+	nodes := listNodes()
+	altaInfo := listAltaInfo()
+	registerAlta(spec)
+
+	// Select the node from current resource state and allocate on it
+	respList, err := rsrcMgr.SelectAndAlloc(func(snapshot rsrcMgr.Snapshot) ([]rsrcMgr.ResourceUse, error) {
+		fillNodeResources(nodes, altaInfo, snapshot)
+
+		nodeList, err := self.RankNodes(spec, nodes)
+		if err != nil {
+			return nil, err
+		}
+
+		nodeAddr = nodeList[0]
+		return AltaResourceList(spec, nodeAddr), nil
+	})
+	if err != nil {
+		log.Errorf("Error scheduling alta %s. Err: %v", spec.AltaId, err)
+		return "", err
+	}
+
+	log.Infof("Picking node %s for Alta: %s", nodeAddr, spec.AltaId)
+
+	// Save what was allocated so that node can use it
+	spec.AllocatedResources = nil
+	for _, resp := range respList {
+		spec.AllocatedResources = append(spec.AllocatedResources, altaspec.AllocatedResource{
+			Type:        resp.Type,
+			NumRsrc:     resp.NumRsrc,
+			RsrcIndexes: resp.RsrcIndexes,
+		})
+	}
+
+	return nodeAddr, nil
+}
+
+// Filter and sort the nodes by score. Returns the node addresses, best first
+func (self *frameworkSched) RankNodes(spec *altaspec.AltaSpec, nodes []*NodeInfo) ([]string, error) {
+	// Check if we have any nodes at all
+	if len(nodes) == 0 {
+		return nil, &ScheduleError{Message: "No nodes to schedule"}
+	}
+
+	// Reject invalid constraints instead of matching nothing
+	_, err := ParseConstraints(spec.SchedPolicy.Filters, spec.SchedPolicy.Constraints)
+	if err != nil {
+		return nil, err
+	}
+
+	// Apply all filters
+	var feasible []*NodeInfo
+	var rejections []common.NodeRejection
+	for _, node := range nodes {
+		if err := self.filterNode(spec, node, nodes); err != nil {
+			log.Debugf("Node %s rejected for alta %s. Reason: %v", node.HostAddr, spec.AltaId, err)
+			rejections = append(rejections, common.NodeRejection{
+				NodeAddr: node.HostAddr,
+				Reason:   err.Error(),
+			})
+			continue
+		}
+		feasible = append(feasible, node)
+	}
+
+	// See if any node passed the filters
+	if len(feasible) == 0 {
+		return nil, &ScheduleError{Message: "No nodes that match the filter", Rejections: rejections}
+	}
+
+	// Add up the normalized scores from each plugin
+	scores := make([]nodeScore, len(feasible))
+	for idx, node := range feasible {
+		scores[idx].node = node
+	}
+	for _, scorer := range self.scorers {
+		rawScores := make([]float64, len(feasible))
+		for idx, node := range feasible {
+			rawScores[idx] = scorer.plugin.Score(spec, node, nodes)
+		}
+
+		for idx, score := range normalizeScores(rawScores) {
+			scores[idx].score += score * scorer.weight
+		}
+	}
+
+	// Sort by score, break ties by node address
+	sort.Sort(byScore(scores))
+
+	nodeList := make([]string, len(scores))
+	for idx, score := range scores {
+		nodeList[idx] = score.node.HostAddr
+	}
+
+	return nodeList, nil
+}
+
+// Run all filters on a node
+func (self *frameworkSched) filterNode(spec *altaspec.AltaSpec, node *NodeInfo, allNodes []*NodeInfo) error {
+	for _, filter := range self.filters {
+		if err := filter.Filter(spec, node, allNodes); err != nil {
+			return fmt.Errorf("%s: %v", filter.Name(), err)
+		}
+	}
+
+	return nil
+}
+
+// Scale raw scores to 0-100 range. If all scores are same, they are all 100
+func normalizeScores(rawScores []float64) []float64 {
+	scores := make([]float64, len(rawScores))
+	if len(rawScores) == 0 {
+		return scores
+	}
+
+	// Find min and max
+	minScore, maxScore := rawScores[0], rawScores[0]
+	for _, score := range rawScores {
+		if score < minScore {
+			minScore = score
+		}
+		if score > maxScore {
+			maxScore = score
+		}
+	}
+
+	for idx, score := range rawScores {
+		if maxScore == minScore {
+			scores[idx] = 100
+		} else {
+			scores[idx] = (score - minScore) * 100 / (maxScore - minScore)
+		}
+	}
+
+	return scores
+}
+
+// Sort nodes by descending score and ascending address
+type byScore []nodeScore
+
+func (s byScore) Len() int      { return len(s) }
+func (s byScore) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s byScore) Less(i, j int) bool {
+	if s[i].score != s[j].score {
+		return s[i].score > s[j].score
+	}
+	return s[i].node.HostAddr < s[j].node.HostAddr
+}
+
+// Return the resources requested by an alta, by resource type
+func requestedResources(spec *altaspec.AltaSpec) map[string]float64 {
+	reqRsrc := map[string]float64{
+		"cpu":    float64(spec.NumCpu),
+		"memory": float64(spec.Memory),
+	}
+
+	for _, rsrc := range spec.SchedPolicy.Resources {
+		reqRsrc[rsrc.Type] += rsrc.NumRsrc
+	}
+
+	return reqRsrc
+}
+
+// Return requested resource types in a predictable order
+func requestedTypes(reqRsrc map[string]float64) []string {
+	var rsrcTypes []string
+	for rsrcType := range reqRsrc {
+		rsrcTypes = append(rsrcTypes, rsrcType)
+	}
+	sort.Strings(rsrcTypes)
+
+	return rsrcTypes
+}
+
+// Build the list of resources used by an alta on a node.
+// Same list is used to allocate and free the resources
+func AltaResourceList(spec *altaspec.AltaSpec, nodeAddr string) []rsrcMgr.ResourceUse {
+	var rsrcList []rsrcMgr.ResourceUse
+
+	reqRsrc := requestedResources(spec)
+	for _, rsrcType := range requestedTypes(reqRsrc) {
+		rsrcList = append(rsrcList, rsrcMgr.ResourceUse{
+			Type:     rsrcType,
+			Provider: nodeAddr,
+			UserKey:  spec.AltaId,
+			NumRsrc:  reqRsrc[rsrcType],
+		})
+	}
+
+	return rsrcList
+}
@@ -0,0 +1,91 @@
+/***
+Copyright 2014 Cisco Systems Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"fmt"
+
+	"github.com/contiv/symphony/pkg/altaspec"
+	"github.com/contiv/symphony/pkg/rsrcMgr"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// Schedule a group of altas all or nothing. Nodes for all members are picked
+// and their resources allocated in one resource manager transaction.
+// Returns the node for each member in the same order as specs
+func ScheduleGang(specs []*altaspec.AltaSpec) ([]string, error) {
+	nodeAddrs := make([]string, len(specs))
+	nodes := listNodes()
+	altaInfo := listAltaInfo()
+	for _, spec := range specs {
+		registerAlta(spec)
+	}
+
+	// Place the members one after another and allocate for all of them
+	respList, err := rsrcMgr.SelectAndAlloc(func(snapshot rsrcMgr.Snapshot) ([]rsrcMgr.ResourceUse, error) {
+		fillNodeResources(nodes, altaInfo, snapshot)
+
+		rsrcList, err := placeGang(specs, nodes, nodeAddrs)
+		if err != nil {
+			return nil, err
+		}
+
+		return rsrcList, nil
+	})
+	if err != nil {
+		log.Errorf("Error scheduling gang of %d altas. Err: %v", len(specs), err)
+		return nil, err
+	}
+
+	log.Infof("Picking nodes %v for gang of altas", nodeAddrs)
+
+	// Save what was allocated for each member
+	for _, spec := range specs {
+		spec.AllocatedResources = nil
+		for _, resp := range respList {
+			if resp.UserKey == spec.AltaId {
+				spec.AllocatedResources = append(spec.AllocatedResources, altaspec.AllocatedResource{
+					Type:        resp.Type,
+					NumRsrc:     resp.NumRsrc,
+					RsrcIndexes: resp.RsrcIndexes,
+				})
+			}
+		}
+	}
+
+	return nodeAddrs, nil
+}
+
+// Pick nodes for all members of a gang. Returns resources to allocate for
+// all members or an error if any member can not be placed
+func placeGang(specs []*altaspec.AltaSpec, nodes []*NodeInfo, nodeAddrs []string) ([]rsrcMgr.ResourceUse, error) {
+	var rsrcList []rsrcMgr.ResourceUse
+
+	for idx, result := range simulateOnNodes(specs, nodes) {
+		if result.NodeAddr == "" {
+			return nil, &ScheduleError{
+				Message:    fmt.Sprintf("Gang member %s can not be placed: %s", result.AltaName, result.Error),
+				Rejections: result.Rejections,
+			}
+		}
+
+		nodeAddrs[idx] = result.NodeAddr
+		rsrcList = append(rsrcList, AltaResourceList(specs[idx], result.NodeAddr)...)
+	}
+
+	return rsrcList, nil
+}
@@ -0,0 +1,305 @@
+/***
+Copyright 2014 Cisco Systems Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"fmt"
+	"math/rand"
+
+	"github.com/contiv/symphony/pkg/altaspec"
+)
+
+// ****************** Filter plugins *****************
+
+// Filter nodes by attribute constraints in scheduler policy
+type attributeFilter struct{}
+
+func (self *attributeFilter) Name() string {
+	return "nodeAttributes"
+}
+
+// Node attributes must satisfy all filters and constraints
+func (self *attributeFilter) Filter(spec *altaspec.AltaSpec, node *NodeInfo, allNodes []*NodeInfo) error {
+	constraints, err := ParseConstraints(spec.SchedPolicy.Filters, spec.SchedPolicy.Constraints)
+	if err != nil {
+		return err
+	}
+
+	for _, constraint := range constraints {
+		if !constraint.Match(node.Attributes) {
+			return fmt.Errorf("node does not satisfy %s", constraint)
+		}
+	}
+
+	return nil
+}
+
+// Filter out the nodes alta is asked to avoid, e.g. the node its migrating from.
+// When alta has a target node, all other nodes are avoided
+type avoidNodeFilter struct{}
+
+func (self *avoidNodeFilter) Name() string {
+	return "avoidNodes"
+}
+
+// Node must not be in the avoid list
+func (self *avoidNodeFilter) Filter(spec *altaspec.AltaSpec, node *NodeInfo, allNodes []*NodeInfo) error {
+	for _, nodeAddr := range spec.SchedPolicy.AvoidNodes {
+		if nodeAddr == node.HostAddr {
+			return fmt.Errorf("alta is moving away from the node")
+		}
+	}
+
+	// Planned migration goes only to its target
+	target := spec.SchedPolicy.TargetNode
+	if (target != "") && (target != node.HostAddr) {
+		return fmt.Errorf("alta is moving to node %s", target)
+	}
+
+	return nil
+}
+
+// Filter nodes that dont have enough free resources
+type resourceFitFilter struct{}
+
+func (self *resourceFitFilter) Name() string {
+	return "resourceFit"
+}
+
+// Node must provide all requested resources and have enough of them free
+func (self *resourceFitFilter) Filter(spec *altaspec.AltaSpec, node *NodeInfo, allNodes []*NodeInfo) error {
+	reqRsrc := requestedResources(spec)
+
+	for _, rsrcType := range requestedTypes(reqRsrc) {
+		if _, ok := node.Total[rsrcType]; !ok {
+			return fmt.Errorf("node does not provide %s", rsrcType)
+		}
+		if node.Free[rsrcType] < reqRsrc[rsrcType] {
+			return fmt.Errorf("insufficient %s: requested %v, free %v", rsrcType,
+				reqRsrc[rsrcType], node.Free[rsrcType])
+		}
+	}
+
+	return nil
+}
+
+// Filter nodes by hard affinity and anti-affinity rules
+type affinityFilter struct{}
+
+func (self *affinityFilter) Name() string {
+	return "altaAffinity"
+}
+
+// Node must run altas matching each hard affinity rule and none matching hard anti-affinity rules
+func (self *affinityFilter) Filter(spec *altaspec.AltaSpec, node *NodeInfo, allNodes []*NodeInfo) error {
+	for _, rule := range spec.SchedPolicy.Affinity {
+		// First alta of a group can go anywhere
+		if rule.Hard && (countAffinityMatch(spec, &rule, node) == 0) &&
+			(countAffinityMatchAll(spec, &rule, allNodes) != 0) {
+			return fmt.Errorf("node has no altas matching affinity %v", rule.Selector)
+		}
+	}
+
+	for _, rule := range spec.SchedPolicy.AntiAffinity {
+		if rule.Hard && (countAffinityMatch(spec, &rule, node) != 0) {
+			return fmt.Errorf("node has altas matching anti-affinity %v", rule.Selector)
+		}
+	}
+
+	return nil
+}
+
+// ****************** Score plugins *****************
+
+// Score nodes by soft affinity and anti-affinity rules
+type affinityScore struct{}
+
+func (self *affinityScore) Name() string {
+	return "altaAffinity"
+}
+
+// Each matching alta on the node adds to affinity and subtracts for anti-affinity
+func (self *affinityScore) Score(spec *altaspec.AltaSpec, node *NodeInfo, allNodes []*NodeInfo) float64 {
+	var score float64
+
+	for _, rule := range spec.SchedPolicy.Affinity {
+		if !rule.Hard {
+			score += float64(countAffinityMatch(spec, &rule, node))
+		}
+	}
+	for _, rule := range spec.SchedPolicy.AntiAffinity {
+		if !rule.Hard {
+			score -= float64(countAffinityMatch(spec, &rule, node))
+		}
+	}
+
+	return score
+}
+
+// Score nodes by free resources of a type
+type freeRsrcScore struct {
+	name     string // Plugin name
+	rsrcType string // Resource type to score
+}
+
+func (self *freeRsrcScore) Name() string {
+	return self.name
+}
+
+// More free resources score higher
+func (self *freeRsrcScore) Score(spec *altaspec.AltaSpec, node *NodeInfo, allNodes []*NodeInfo) float64 {
+	return node.Free[self.rsrcType]
+}
+
+// Score nodes by free resources compared one type at a time. Next type is
+// compared only when the nodes have same amount of the previous type free
+type orderedFreeRsrcScore struct {
+	name      string   // Plugin name
+	rsrcTypes []string // Resource types in the order they are compared
+}
+
+func (self *orderedFreeRsrcScore) Name() string {
+	return self.name
+}
+
+// Score is the number of nodes with less free resources, so that
+// normalizing the scores does not change the order
+func (self *orderedFreeRsrcScore) Score(spec *altaspec.AltaSpec, node *NodeInfo, allNodes []*NodeInfo) float64 {
+	var score float64
+	for _, otherNode := range allNodes {
+		if self.lessFree(otherNode, node) {
+			score++
+		}
+	}
+
+	return score
+}
+
+// Check if node1 has less free resources than node2
+func (self *orderedFreeRsrcScore) lessFree(node1, node2 *NodeInfo) bool {
+	for _, rsrcType := range self.rsrcTypes {
+		if node1.Free[rsrcType] != node2.Free[rsrcType] {
+			return node1.Free[rsrcType] < node2.Free[rsrcType]
+		}
+	}
+
+	return false
+}
+
+// Score nodes randomly
+type randomScore struct{}
+
+func (self *randomScore) Name() string {
+	return "random"
+}
+
+func (self *randomScore) Score(spec *altaspec.AltaSpec, node *NodeInfo, allNodes []*NodeInfo) float64 {
+	return rand.Float64()
+}
+
+// Score nodes by how many instances of the same service are in the node's
+// failure domain. Domain is the value of spread key attribute on the node
+type spreadScore struct{}
+
+func (self *spreadScore) Name() string {
+	return "spreadDomain"
+}
+
+// Fewer peers in the domain score higher. Within a domain, fewer peers on the node score higher
+func (self *spreadScore) Score(spec *altaspec.AltaSpec, node *NodeInfo, allNodes []*NodeInfo) float64 {
+	spreadKey := spec.SchedPolicy.SpreadKey
+	domain := node.Attributes[spreadKey]
+
+	// Count the peers in node's domain and on the node itself
+	var numPeers, domainPeers, nodePeers float64
+	for _, otherNode := range allNodes {
+		for _, placement := range otherNode.Altas {
+			if !isPeerAlta(spec, &placement) {
+				continue
+			}
+
+			numPeers++
+			if otherNode.Attributes[spreadKey] == domain {
+				domainPeers++
+			}
+			if otherNode.HostAddr == node.HostAddr {
+				nodePeers++
+			}
+		}
+	}
+
+	return -(domainPeers*(numPeers+1) + nodePeers)
+}
+
+// Check if a placed alta belongs to the same service or job as the alta being scheduled
+func isPeerAlta(spec *altaspec.AltaSpec, placement *AltaPlacement) bool {
+	if placement.AltaId == spec.AltaId {
+		return false
+	}
+
+	// Altas of the same service
+	if spec.Labels[altaspec.ServiceLabel] != "" {
+		for _, label := range []string{altaspec.TenantLabel, altaspec.AppLabel, altaspec.ServiceLabel} {
+			if placement.Labels[label] != spec.Labels[label] {
+				return false
+			}
+		}
+		return true
+	}
+
+	// Altas of the same job
+	if spec.JobName != "" {
+		return placement.JobName == spec.JobName
+	}
+
+	return false
+}
+
+// Count the altas on a node matching an affinity rule
+func countAffinityMatch(spec *altaspec.AltaSpec, rule *altaspec.AltaAffinity, node *NodeInfo) int {
+	count := 0
+	for _, placement := range node.Altas {
+		if (placement.AltaId != spec.AltaId) && labelsMatch(rule.Selector, placement.Labels) {
+			count++
+		}
+	}
+
+	return count
+}
+
+// Count the altas on all nodes matching an affinity rule
+func countAffinityMatchAll(spec *altaspec.AltaSpec, rule *altaspec.AltaAffinity, nodes []*NodeInfo) int {
+	count := 0
+	for _, node := range nodes {
+		count += countAffinityMatch(spec, rule, node)
+	}
+
+	return count
+}
+
+// Check if labels have all the selector labels. Empty selector matches nothing
+func labelsMatch(selector, labels map[string]string) bool {
+	if len(selector) == 0 {
+		return false
+	}
+	for key, value := range selector {
+		if labels[key] != value {
+			return false
+		}
+	}
+
+	return true
+}
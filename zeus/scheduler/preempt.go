@@ -0,0 +1,226 @@
+/***
+Copyright 2014 Cisco Systems Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/contiv/symphony/pkg/altaspec"
+	"github.com/contiv/symphony/pkg/rsrcMgr"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// ****************** Priority and preemption *****************
+
+// Well known priority classes
+var priorityClasses = map[string]int{
+	"critical":   1000,
+	"production": 100,
+	"default":    0,
+	"dev":        -100,
+}
+
+// Max number of altas that can be evicted in a minute across the cluster
+var MaxEvictionsPerMin = 10
+
+// Altas to evict from a node so that a higher priority alta fits
+type Preemption struct {
+	NodeAddr string   // Node where the alta will fit
+	Victims  []string // Alta ids to evict, lowest priority first
+}
+
+// Recent evictions for rate limiting
+var evictionMutex sync.Mutex
+var evictionTimes []time.Time
+
+// Return the priority of a named priority class
+func PriorityClassValue(className string) (int, error) {
+	if className == "" {
+		return 0, nil
+	}
+
+	priority, ok := priorityClasses[className]
+	if !ok {
+		log.Errorf("Priority class %s not found", className)
+		return 0, errors.New("Priority class not found")
+	}
+
+	return priority, nil
+}
+
+// Find lower priority altas to evict so that the alta can be scheduled.
+// Number of victims is limited by the evictions left in the rate limit.
+// Caller records each eviction once the victim has left its node
+func FindPreemption(spec *altaspec.AltaSpec) (*Preemption, error) {
+	sched, err := Scheduler(spec.SchedPolicy.SchedulerName)
+	if err != nil {
+		return nil, err
+	}
+
+	evictionMutex.Lock()
+	defer evictionMutex.Unlock()
+
+	// See how many evictions we are allowed
+	budget := MaxEvictionsPerMin - pruneEvictions(time.Now())
+	if budget <= 0 {
+		log.Warnf("Eviction rate limit reached, not preempting for alta %s", spec.AltaId)
+		return nil, errors.New("Eviction rate limit reached")
+	}
+
+	nodes := listNodes()
+	fillNodeResources(nodes, listAltaInfo(), rsrcMgr.GetSnapshot())
+
+	return findPreemption(sched, spec, nodes, budget)
+}
+
+// Count an eviction against the rate limit
+func RecordEviction() {
+	evictionMutex.Lock()
+	defer evictionMutex.Unlock()
+
+	evictionTimes = append(evictionTimes, time.Now())
+}
+
+// Forget evictions older than a minute. Returns number of recent evictions
+func pruneEvictions(now time.Time) int {
+	var recent []time.Time
+	for _, evictTime := range evictionTimes {
+		if now.Sub(evictTime) < time.Minute {
+			recent = append(recent, evictTime)
+		}
+	}
+	evictionTimes = recent
+
+	return len(evictionTimes)
+}
+
+// Pick the node that needs the least disruptive set of evictions.
+// Only altas with strictly lower priority are considered and nodes needing
+// more than maxVictims evictions are skipped
+func findPreemption(sched SchedulerIntf, spec *altaspec.AltaSpec, nodes []*NodeInfo,
+	maxVictims int) (*Preemption, error) {
+	var best *Preemption
+	var bestPriority int
+
+	for idx, node := range nodes {
+		// Lower priority altas on the node, lowest priority first
+		var candidates []AltaPlacement
+		for _, placement := range node.Altas {
+			if placement.Preemptible && (placement.Priority < spec.SchedPolicy.Priority) {
+				candidates = append(candidates, placement)
+			}
+		}
+		if len(candidates) == 0 {
+			continue
+		}
+		sort.Sort(byPriority(candidates))
+
+		// Evict one alta at a time till the alta fits on the node
+		trial := *node
+		trial.Free = make(map[string]float64)
+		for rsrcType, numRsrc := range node.Free {
+			trial.Free[rsrcType] = numRsrc
+		}
+		trialNodes := make([]*NodeInfo, len(nodes))
+		copy(trialNodes, nodes)
+		trialNodes[idx] = &trial
+
+		var victims []string
+		for cidx, victim := range candidates {
+			if len(victims) >= maxVictims {
+				break
+			}
+
+			victims = append(victims, victim.AltaId)
+			for rsrcType, numRsrc := range victim.Resources {
+				trial.Free[rsrcType] += numRsrc
+			}
+			trial.Altas = append(append([]AltaPlacement{}, candidates[cidx+1:]...),
+				nonCandidates(node.Altas, candidates)...)
+
+			if !rankedNode(sched, spec, trialNodes, node.HostAddr) {
+				continue
+			}
+
+			// Prefer lower max victim priority, then fewer victims, then node address
+			priority := victim.Priority
+			if (best == nil) || (priority < bestPriority) ||
+				((priority == bestPriority) && (len(victims) < len(best.Victims))) {
+				best = &Preemption{NodeAddr: node.HostAddr, Victims: victims}
+				bestPriority = priority
+			}
+			break
+		}
+	}
+
+	if best == nil {
+		log.Infof("No preemption found for alta %s", spec.AltaId)
+		return nil, errors.New("No lower priority altas to preempt")
+	}
+
+	log.Infof("Preempting altas %v on node %s for alta %s", best.Victims, best.NodeAddr, spec.AltaId)
+
+	return best, nil
+}
+
+// Altas on the node that are not eviction candidates
+func nonCandidates(altas []AltaPlacement, candidates []AltaPlacement) []AltaPlacement {
+	isCandidate := make(map[string]bool)
+	for _, candidate := range candidates {
+		isCandidate[candidate.AltaId] = true
+	}
+
+	var others []AltaPlacement
+	for _, placement := range altas {
+		if !isCandidate[placement.AltaId] {
+			others = append(others, placement)
+		}
+	}
+
+	return others
+}
+
+// Check if the node passes all filters for the alta
+func rankedNode(sched SchedulerIntf, spec *altaspec.AltaSpec, nodes []*NodeInfo, nodeAddr string) bool {
+	nodeList, err := sched.RankNodes(spec, nodes)
+	if err != nil {
+		return false
+	}
+
+	for _, addr := range nodeList {
+		if addr == nodeAddr {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Sort placements by ascending priority and alta id
+type byPriority []AltaPlacement
+
+func (s byPriority) Len() int      { return len(s) }
+func (s byPriority) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s byPriority) Less(i, j int) bool {
+	if s[i].Priority != s[j].Priority {
+		return s[i].Priority < s[j].Priority
+	}
+	return s[i].AltaId < s[j].AltaId
+}
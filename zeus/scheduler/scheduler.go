@@ -16,6 +16,8 @@ limitations under the License.
 package scheduler
 
 import (
+	"errors"
+
 	"github.com/contiv/symphony/pkg/altaspec"
 
 	log "github.com/Sirupsen/logrus"
@@ -23,40 +25,127 @@ import (
 
 // Responsible for scheduling Alta containers on a node
 // Scheduler is designed to support multiple scheduling policies
-// with default being "leastUsed"
+// with default being "leastUsed". Each policy is a composition of
+// filter plugins and weighted score plugins
 
 // Define the scheduler interface
 type SchedulerIntf interface {
+	// Pick a node and allocate resources for the alta
 	ScheduleAlta(spec *altaspec.AltaSpec) (string, error)
+
+	// Rank the nodes for the alta without allocating anything
+	RankNodes(spec *altaspec.AltaSpec, nodes []*NodeInfo) ([]string, error)
+}
+
+// Score plugin and its weight in a policy
+type ScoreWeight struct {
+	Name   string  // Name of the score plugin
+	Weight float64 // Weight of the plugin's score
 }
 
-// DB of supported scheduler
-var schedulers map[string]SchedulerIntf = make(map[string]SchedulerIntf)
+// DB of registered plugins and schedulers
+var filterPlugins map[string]FilterPlugin
+var scorePlugins map[string]ScorePlugin
+var schedulers map[string]SchedulerIntf
 var defaultScheduler SchedulerIntf
 
-// Initialize all known schedulers
+// Initialize all known plugins and scheduling policies
 func Init() {
-	// Initialize each scheduler type
-	schedulers["leastUsed"] = NewLeastUsedScheduler()
-	schedulers["binPack"] = NewBinPackScheduler()
+	filterPlugins = make(map[string]FilterPlugin)
+	scorePlugins = make(map[string]ScorePlugin)
+	schedulers = make(map[string]SchedulerIntf)
+	schedAltaDb = make(map[string]AltaPlacement)
+
+	// Register the plugins
+	RegisterFilterPlugin(&attributeFilter{})
+	RegisterFilterPlugin(&resourceFitFilter{})
+	RegisterFilterPlugin(&affinityFilter{})
+	RegisterFilterPlugin(&avoidNodeFilter{})
+	RegisterScorePlugin(&freeRsrcScore{name: "leastUsedCpu", rsrcType: "cpu"})
+	RegisterScorePlugin(&freeRsrcScore{name: "leastUsedMemory", rsrcType: "memory"})
+	RegisterScorePlugin(&orderedFreeRsrcScore{name: "leastUsed", rsrcTypes: []string{"cpu", "memory"}})
+	RegisterScorePlugin(&randomScore{})
+	RegisterScorePlugin(&spreadScore{})
+	RegisterScorePlugin(&affinityScore{})
+
+	// All policies apply the same filters and honor soft affinity rules
+	filters := []string{"nodeAttributes", "avoidNodes", "resourceFit", "altaAffinity"}
+	affinity := ScoreWeight{"altaAffinity", 5}
+
+	// Pick the node with most free cpu. Free memory breaks the ties
+	RegisterPolicy("leastUsed", filters,
+		[]ScoreWeight{affinity, {"leastUsed", 1}})
+
+	// Pack containers into as few nodes as possible. Without a resource score
+	// ties go to the lowest address, i.e. first node that fits in address order
+	RegisterPolicy("binPack", filters,
+		[]ScoreWeight{affinity})
+
+	// Pick a random node that fits
+	RegisterPolicy("random", filters,
+		[]ScoreWeight{affinity, {"random", 1}})
+
+	// Spread instances of a service across failure domains, then least used nodes
+	RegisterPolicy("spread", filters,
+		[]ScoreWeight{affinity, {"spreadDomain", 10}, {"leastUsedCpu", 2}, {"leastUsedMemory", 1}})
 
 	// set the default
 	defaultScheduler = schedulers["leastUsed"]
 }
 
+// Register a filter plugin
+func RegisterFilterPlugin(plugin FilterPlugin) {
+	filterPlugins[plugin.Name()] = plugin
+}
+
+// Register a score plugin
+func RegisterScorePlugin(plugin ScorePlugin) {
+	scorePlugins[plugin.Name()] = plugin
+}
+
+// Register a scheduling policy composed of registered plugins
+func RegisterPolicy(policyName string, filters []string, scores []ScoreWeight) error {
+	sched := new(frameworkSched)
+	sched.policyName = policyName
+
+	// Lookup the filter plugins
+	for _, filterName := range filters {
+		plugin := filterPlugins[filterName]
+		if plugin == nil {
+			log.Errorf("Filter plugin %s not found for policy %s", filterName, policyName)
+			return errors.New("Filter plugin not found")
+		}
+		sched.filters = append(sched.filters, plugin)
+	}
+
+	// Lookup the score plugins
+	for _, score := range scores {
+		plugin := scorePlugins[score.Name]
+		if plugin == nil {
+			log.Errorf("Score plugin %s not found for policy %s", score.Name, policyName)
+			return errors.New("Score plugin not found")
+		}
+		sched.scorers = append(sched.scorers, weightedScorer{plugin, score.Weight})
+	}
+
+	schedulers[policyName] = sched
+
+	return nil
+}
+
 // Return a scheduler instance
-func Scheduler(schedPolicy string) SchedulerIntf {
+func Scheduler(schedPolicy string) (SchedulerIntf, error) {
 	// Return default if a policy wasnt specified
 	if (schedPolicy == "") || (schedPolicy == "default") {
-		return defaultScheduler
+		return defaultScheduler, nil
 	}
 
 	// Return the scheduler specified
 	if schedulers[schedPolicy] != nil {
-		return schedulers[schedPolicy]
+		return schedulers[schedPolicy], nil
 	}
 
 	// Error case
-	log.Fatal("Scheduler policy not found")
-	return nil
+	log.Errorf("Scheduler policy %s not found", schedPolicy)
+	return nil, errors.New("Scheduler policy not found")
 }
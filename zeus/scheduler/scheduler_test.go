@@ -0,0 +1,694 @@
+package scheduler
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/contiv/symphony/pkg/altaspec"
+	"github.com/contiv/symphony/pkg/rsrcMgr"
+)
+
+// Create a node info for tests
+func testNode(hostAddr string, freeCpu, freeMem float64, attr map[string]string) *NodeInfo {
+	return &NodeInfo{
+		HostAddr:   hostAddr,
+		Attributes: attr,
+		Total:      map[string]float64{"cpu": 8, "memory": 8192},
+		Free:       map[string]float64{"cpu": freeCpu, "memory": freeMem},
+	}
+}
+
+// Resource manager can only be initialized once
+var rsrcMgrOnce sync.Once
+
+func initRsrcMgr() {
+	rsrcMgrOnce.Do(func() { rsrcMgr.Init(nil) })
+}
+
+// Rank nodes using a policy
+func testRank(t *testing.T, policy string, spec *altaspec.AltaSpec, nodes []*NodeInfo) []string {
+	sched, err := Scheduler(policy)
+	if err != nil {
+		t.Fatalf("Error getting scheduler %s. Err: %v", policy, err)
+	}
+
+	nodeList, err := sched.RankNodes(spec, nodes)
+	if err != nil {
+		t.Fatalf("Error ranking nodes. Err: %v", err)
+	}
+
+	return nodeList
+}
+
+// Test unknown policies return an error
+func TestUnknownPolicy(t *testing.T) {
+	Init()
+
+	sched, err := Scheduler("noSuchPolicy")
+	if err == nil || sched != nil {
+		t.Errorf("Unknown policy did not return an error")
+	}
+
+	sched, err = Scheduler("")
+	if err != nil || sched == nil {
+		t.Errorf("Error getting default scheduler. Err: %v", err)
+	}
+
+	err = RegisterPolicy("badPolicy", []string{"noSuchFilter"}, nil)
+	if err == nil {
+		t.Errorf("Policy with unknown plugin registered without error")
+	}
+}
+
+// Test filter plugins
+func TestFilterPlugins(t *testing.T) {
+	Init()
+
+	spec := altaspec.AltaSpec{
+		AltaId: "test",
+		NumCpu: 2,
+		Memory: 1024,
+	}
+	spec.SchedPolicy.Filters = map[string]string{"zone": "a"}
+
+	nodes := []*NodeInfo{
+		testNode("10.1.1.1", 4, 4096, map[string]string{"zone": "b"}),
+		testNode("10.1.1.2", 1, 4096, map[string]string{"zone": "a"}),
+		testNode("10.1.1.3", 4, 512, map[string]string{"zone": "a"}),
+		testNode("10.1.1.4", 4, 4096, map[string]string{"zone": "a"}),
+		testNode("10.1.1.5", 4, 4096, nil),
+	}
+
+	nodeList := testRank(t, "leastUsed", &spec, nodes)
+	if len(nodeList) != 1 || nodeList[0] != "10.1.1.4" {
+		t.Errorf("Unexpected filter result: %v", nodeList)
+	}
+
+	// No nodes match
+	spec.SchedPolicy.Filters = map[string]string{"zone": "c"}
+	sched, _ := Scheduler("leastUsed")
+	_, err := sched.RankNodes(&spec, nodes)
+	if err == nil {
+		t.Errorf("Expected error when no nodes match")
+	}
+}
+
+// Test scoring and tie breaking
+func TestScorePlugins(t *testing.T) {
+	Init()
+
+	spec := altaspec.AltaSpec{
+		AltaId: "test",
+		NumCpu: 1,
+		Memory: 256,
+	}
+
+	nodes := []*NodeInfo{
+		testNode("10.1.1.3", 2, 4096, nil),
+		testNode("10.1.1.2", 6, 1024, nil),
+		testNode("10.1.1.1", 6, 1024, nil),
+		testNode("10.1.1.4", 4, 2048, nil),
+	}
+
+	// Least used prefers free cpu over free memory, ties broken by address
+	nodeList := testRank(t, "leastUsed", &spec, nodes)
+	expList := []string{"10.1.1.1", "10.1.1.2", "10.1.1.4", "10.1.1.3"}
+	for idx, nodeAddr := range expList {
+		if nodeList[idx] != nodeAddr {
+			t.Errorf("leastUsed: expected %v, got %v", expList, nodeList)
+			break
+		}
+	}
+
+	// Free cpu wins even if another node has a lot more memory free
+	nodes = []*NodeInfo{
+		testNode("10.1.1.1", 2, 8192, nil),
+		testNode("10.1.1.2", 7, 8192, nil),
+		testNode("10.1.1.3", 8, 1024, nil),
+	}
+	nodeList = testRank(t, "leastUsed", &spec, nodes)
+	expList = []string{"10.1.1.3", "10.1.1.2", "10.1.1.1"}
+	for idx, nodeAddr := range expList {
+		if nodeList[idx] != nodeAddr {
+			t.Errorf("leastUsed: expected %v, got %v", expList, nodeList)
+			break
+		}
+	}
+
+	// Bin packing picks the first node that fits in address order
+	nodes = []*NodeInfo{
+		testNode("10.1.1.3", 2, 2048, nil),
+		testNode("10.1.1.1", 6, 6144, nil),
+		testNode("10.1.1.4", 4, 4096, nil),
+		testNode("10.1.1.2", 0, 2048, nil),
+	}
+	nodeList = testRank(t, "binPack", &spec, nodes)
+	expList = []string{"10.1.1.1", "10.1.1.3", "10.1.1.4"}
+	for idx, nodeAddr := range expList {
+		if nodeList[idx] != nodeAddr {
+			t.Errorf("binPack: expected %v, got %v", expList, nodeList)
+			break
+		}
+	}
+}
+
+// Test score normalization
+func TestNormalizeScores(t *testing.T) {
+	scores := normalizeScores([]float64{10, 20, 30})
+	if scores[0] != 0 || scores[1] != 50 || scores[2] != 100 {
+		t.Errorf("Unexpected normalized scores: %v", scores)
+	}
+
+	scores = normalizeScores([]float64{5, 5})
+	if scores[0] != 100 || scores[1] != 100 {
+		t.Errorf("Unexpected normalized scores for equal input: %v", scores)
+	}
+}
+
+// Test random policy picks only feasible nodes
+func TestRandomPolicy(t *testing.T) {
+	Init()
+
+	spec := altaspec.AltaSpec{
+		AltaId: "test",
+		NumCpu: 2,
+		Memory: 1024,
+	}
+
+	nodes := []*NodeInfo{
+		testNode("10.1.1.1", 1, 4096, nil),
+		testNode("10.1.1.2", 4, 4096, nil),
+		testNode("10.1.1.3", 4, 4096, nil),
+	}
+
+	for i := 0; i < 10; i++ {
+		nodeList := testRank(t, "random", &spec, nodes)
+		if len(nodeList) != 2 || nodeList[0] == "10.1.1.1" || nodeList[1] == "10.1.1.1" {
+			t.Fatalf("Unexpected random result: %v", nodeList)
+		}
+	}
+}
+
+// Test spread policy distributes a service across zones
+func TestSpreadPolicy(t *testing.T) {
+	Init()
+
+	svcLabels := map[string]string{
+		altaspec.TenantLabel:  "default",
+		altaspec.AppLabel:     "app",
+		altaspec.ServiceLabel: "web",
+	}
+	otherLabels := map[string]string{
+		altaspec.TenantLabel:  "default",
+		altaspec.AppLabel:     "app",
+		altaspec.ServiceLabel: "db",
+	}
+
+	spec := altaspec.AltaSpec{
+		AltaId: "web3",
+		NumCpu: 1,
+		Memory: 256,
+		Labels: svcLabels,
+	}
+	spec.SchedPolicy.SpreadKey = "zone"
+
+	nodes := []*NodeInfo{
+		testNode("10.1.1.1", 8, 8192, map[string]string{"zone": "a"}),
+		testNode("10.1.1.2", 2, 1024, map[string]string{"zone": "a"}),
+		testNode("10.1.1.3", 8, 8192, map[string]string{"zone": "b"}),
+		testNode("10.1.1.4", 2, 1024, map[string]string{"zone": "c"}),
+	}
+
+	// One web instance in zone a and one in zone b. Many db instances in zone c
+	nodes[1].Altas = []AltaPlacement{{AltaId: "web1", NodeAddr: "10.1.1.2", Labels: svcLabels}}
+	nodes[2].Altas = []AltaPlacement{{AltaId: "web2", NodeAddr: "10.1.1.3", Labels: svcLabels}}
+	nodes[3].Altas = []AltaPlacement{
+		{AltaId: "db1", NodeAddr: "10.1.1.4", Labels: otherLabels},
+		{AltaId: "db2", NodeAddr: "10.1.1.4", Labels: otherLabels},
+	}
+
+	// Zone c has no web instances, even though its the most used node
+	nodeList := testRank(t, "spread", &spec, nodes)
+	if nodeList[0] != "10.1.1.4" {
+		t.Errorf("Spread picked %s instead of the empty zone. Ranking: %v", nodeList[0], nodeList)
+	}
+
+	// With zone c full, zone a node without an instance should be preferred over node in zone b
+	nodes[3].Altas = append(nodes[3].Altas, AltaPlacement{AltaId: "web4", NodeAddr: "10.1.1.4", Labels: svcLabels})
+	nodes[2].Altas = append(nodes[2].Altas, AltaPlacement{AltaId: "web5", NodeAddr: "10.1.1.3", Labels: svcLabels})
+	nodeList = testRank(t, "spread", &spec, nodes)
+	if nodeList[0] != "10.1.1.1" {
+		t.Errorf("Spread picked %s instead of empty node in least used zone. Ranking: %v", nodeList[0], nodeList)
+	}
+}
+
+// Test affinity and anti-affinity rules
+func TestAffinity(t *testing.T) {
+	Init()
+
+	webLabels := map[string]string{altaspec.ServiceLabel: "web"}
+	cacheLabels := map[string]string{altaspec.ServiceLabel: "cache"}
+
+	spec := altaspec.AltaSpec{
+		AltaId: "web2",
+		NumCpu: 1,
+		Memory: 256,
+		Labels: webLabels,
+	}
+	spec.SchedPolicy.AntiAffinity = []altaspec.AltaAffinity{{Selector: webLabels, Hard: true}}
+
+	nodes := []*NodeInfo{
+		testNode("10.1.1.1", 8, 8192, nil),
+		testNode("10.1.1.2", 4, 4096, nil),
+		testNode("10.1.1.3", 2, 2048, nil),
+	}
+	nodes[0].Altas = []AltaPlacement{{AltaId: "web1", NodeAddr: "10.1.1.1", Labels: webLabels}}
+	nodes[2].Altas = []AltaPlacement{{AltaId: "cache1", NodeAddr: "10.1.1.3", Labels: cacheLabels}}
+
+	// Hard anti-affinity excludes the node with another web instance
+	nodeList := testRank(t, "leastUsed", &spec, nodes)
+	if len(nodeList) != 2 || nodeList[0] != "10.1.1.2" {
+		t.Errorf("Unexpected anti-affinity result: %v", nodeList)
+	}
+
+	// Soft affinity with cache prefers the cache node even though its most used
+	spec.SchedPolicy.Affinity = []altaspec.AltaAffinity{{Selector: cacheLabels}}
+	nodeList = testRank(t, "leastUsed", &spec, nodes)
+	if nodeList[0] != "10.1.1.3" {
+		t.Errorf("Unexpected soft affinity result: %v", nodeList)
+	}
+
+	// Hard affinity only allows the cache node
+	spec.SchedPolicy.Affinity = []altaspec.AltaAffinity{{Selector: cacheLabels, Hard: true}}
+	nodeList = testRank(t, "leastUsed", &spec, nodes)
+	if len(nodeList) != 1 || nodeList[0] != "10.1.1.3" {
+		t.Errorf("Unexpected hard affinity result: %v", nodeList)
+	}
+
+	// Hard affinity to altas that dont exist yet allows any node
+	spec.SchedPolicy.AntiAffinity = nil
+	spec.SchedPolicy.Affinity = []altaspec.AltaAffinity{{Selector: map[string]string{"app": "none"}, Hard: true}}
+	nodeList = testRank(t, "leastUsed", &spec, nodes)
+	if len(nodeList) != 3 {
+		t.Errorf("Unexpected hard affinity result with no matching altas: %v", nodeList)
+	}
+}
+
+// Test filtering on additional resource types
+func TestExtraResources(t *testing.T) {
+	Init()
+
+	spec := altaspec.AltaSpec{
+		AltaId: "test",
+		NumCpu: 1,
+		Memory: 256,
+	}
+	spec.SchedPolicy.Resources = []altaspec.Resource{{Type: "license", NumRsrc: 2}}
+
+	nodes := []*NodeInfo{
+		testNode("10.1.1.1", 8, 8192, nil),
+		testNode("10.1.1.2", 4, 4096, nil),
+		testNode("10.1.1.3", 4, 4096, nil),
+	}
+	nodes[1].Total["license"] = 4
+	nodes[1].Free["license"] = 1
+	nodes[2].Total["license"] = 4
+	nodes[2].Free["license"] = 3
+
+	// Only the node with enough free licenses fits
+	nodeList := testRank(t, "leastUsed", &spec, nodes)
+	if len(nodeList) != 1 || nodeList[0] != "10.1.1.3" {
+		t.Errorf("Unexpected extra resource result: %v", nodeList)
+	}
+
+	// Resource list has all requested resources in order
+	rsrcList := AltaResourceList(&spec, "10.1.1.3")
+	expTypes := []string{"cpu", "license", "memory"}
+	if len(rsrcList) != len(expTypes) {
+		t.Fatalf("Unexpected resource list: %+v", rsrcList)
+	}
+	for idx, rsrc := range rsrcList {
+		if rsrc.Type != expTypes[idx] || rsrc.Provider != "10.1.1.3" || rsrc.UserKey != "test" {
+			t.Errorf("Unexpected resource %+v at %d", rsrc, idx)
+		}
+	}
+	if rsrcList[1].NumRsrc != 2 {
+		t.Errorf("Unexpected license count %v", rsrcList[1].NumRsrc)
+	}
+}
+
+// Schedule hundreds of altas concurrently. Run with -race
+func TestConcurrentSchedule(t *testing.T) {
+	Init()
+	initRsrcMgr()
+
+	// Add 5 nodes with 20 cpus each
+	var provider []rsrcMgr.ResourceProvide
+	for i := 0; i < 5; i++ {
+		nodeAddr := fmt.Sprintf("10.1.1.%d", i)
+		provider = append(provider, rsrcMgr.ResourceProvide{
+			Type: "cpu", Provider: nodeAddr, UnitType: "fluid", NumRsrc: 20,
+		})
+		provider = append(provider, rsrcMgr.ResourceProvide{
+			Type: "memory", Provider: nodeAddr, UnitType: "fluid", NumRsrc: 1024 * 1024,
+		})
+	}
+	err := rsrcMgr.AddResourceProvider(provider)
+	if err != nil {
+		t.Fatalf("Error adding providers. Err: %v", err)
+	}
+
+	// Nodes without resources. Resources come from the snapshot
+	listNodes = func() []*NodeInfo {
+		var nodes []*NodeInfo
+		for i := 0; i < 5; i++ {
+			nodes = append(nodes, &NodeInfo{HostAddr: fmt.Sprintf("10.1.1.%d", i)})
+		}
+		return nodes
+	}
+	defer func() { listNodes = listAliveNodes }()
+
+	sched, _ := Scheduler("leastUsed")
+
+	// Schedule 300 altas that need a cpu each
+	var wg sync.WaitGroup
+	var mutex sync.Mutex
+	nodeCount := make(map[string]int)
+	for i := 0; i < 300; i++ {
+		wg.Add(1)
+		go func(idx int) {
+			defer wg.Done()
+
+			spec := altaspec.AltaSpec{
+				AltaId: fmt.Sprintf("alta%d", idx),
+				NumCpu: 1,
+				Memory: 1024,
+			}
+			nodeAddr, err := sched.ScheduleAlta(&spec)
+			if err == nil {
+				if len(spec.AllocatedResources) != 2 {
+					t.Errorf("Unexpected allocated resources: %+v", spec.AllocatedResources)
+				}
+
+				mutex.Lock()
+				nodeCount[nodeAddr]++
+				mutex.Unlock()
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	// Each node should have exactly 20 altas
+	for i := 0; i < 5; i++ {
+		nodeAddr := fmt.Sprintf("10.1.1.%d", i)
+		if nodeCount[nodeAddr] != 20 {
+			t.Errorf("Node %s has %d altas, expected 20", nodeAddr, nodeCount[nodeAddr])
+		}
+
+		cpuProvider := rsrcMgr.FindResourceProvider("cpu", nodeAddr)
+		if cpuProvider.FreeRsrc != 0 {
+			t.Errorf("Node %s has %v free cpu, expected 0", nodeAddr, cpuProvider.FreeRsrc)
+		}
+	}
+}
+
+// Schedule anti-affine replicas concurrently. No two of them may share a node
+func TestConcurrentAntiAffinity(t *testing.T) {
+	Init()
+	initRsrcMgr()
+
+	// Add 3 nodes with plenty of resources
+	var provider []rsrcMgr.ResourceProvide
+	for i := 0; i < 3; i++ {
+		nodeAddr := fmt.Sprintf("10.1.2.%d", i)
+		provider = append(provider, rsrcMgr.ResourceProvide{
+			Type: "cpu", Provider: nodeAddr, UnitType: "fluid", NumRsrc: 20,
+		})
+		provider = append(provider, rsrcMgr.ResourceProvide{
+			Type: "memory", Provider: nodeAddr, UnitType: "fluid", NumRsrc: 1024 * 1024,
+		})
+	}
+	err := rsrcMgr.AddResourceProvider(provider)
+	if err != nil {
+		t.Fatalf("Error adding providers. Err: %v", err)
+	}
+
+	listNodes = func() []*NodeInfo {
+		var nodes []*NodeInfo
+		for i := 0; i < 3; i++ {
+			nodes = append(nodes, &NodeInfo{HostAddr: fmt.Sprintf("10.1.2.%d", i)})
+		}
+		return nodes
+	}
+	defer func() { listNodes = listAliveNodes }()
+
+	sched, _ := Scheduler("binPack")
+	webLabels := map[string]string{"app": "web"}
+
+	// Schedule more replicas than there are nodes, all at once
+	var wg sync.WaitGroup
+	var mutex sync.Mutex
+	nodeCount := make(map[string]int)
+	numFailed := 0
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func(idx int) {
+			defer wg.Done()
+
+			spec := altaspec.AltaSpec{
+				AltaId: fmt.Sprintf("web%d", idx),
+				Labels: webLabels,
+				NumCpu: 1,
+				Memory: 1024,
+			}
+			spec.SchedPolicy.AntiAffinity = []altaspec.AltaAffinity{{Selector: webLabels, Hard: true}}
+
+			nodeAddr, err := sched.ScheduleAlta(&spec)
+
+			mutex.Lock()
+			defer mutex.Unlock()
+			if err != nil {
+				numFailed++
+			} else {
+				nodeCount[nodeAddr]++
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	// Each node gets exactly one replica, rest of them cant be placed
+	if len(nodeCount) != 3 || numFailed != 2 {
+		t.Errorf("Unexpected placement %v with %d failures", nodeCount, numFailed)
+	}
+	for nodeAddr, count := range nodeCount {
+		if count != 1 {
+			t.Errorf("Node %s has %d replicas, expected 1", nodeAddr, count)
+		}
+	}
+}
+
+// Test simulating placement of multiple altas
+func TestSimulate(t *testing.T) {
+	Init()
+
+	svcLabels := map[string]string{altaspec.ServiceLabel: "web"}
+
+	var specs []*altaspec.AltaSpec
+	for i := 0; i < 3; i++ {
+		spec := altaspec.AltaSpec{
+			AltaId:   fmt.Sprintf("web%d", i),
+			AltaName: fmt.Sprintf("web%d", i),
+			NumCpu:   2,
+			Memory:   1024,
+			Labels:   svcLabels,
+		}
+		spec.SchedPolicy.AntiAffinity = []altaspec.AltaAffinity{{Selector: svcLabels, Hard: true}}
+		specs = append(specs, &spec)
+	}
+
+	nodes := []*NodeInfo{
+		testNode("10.1.1.1", 4, 4096, nil),
+		testNode("10.1.1.2", 4, 4096, nil),
+	}
+
+	// Two instances fit, third is rejected by anti-affinity on both nodes
+	results := simulateOnNodes(specs, nodes)
+	if results[0].NodeAddr != "10.1.1.1" || results[1].NodeAddr != "10.1.1.2" {
+		t.Errorf("Unexpected placement: %+v, %+v", results[0], results[1])
+	}
+	if results[2].NodeAddr != "" || len(results[2].Rejections) != 2 {
+		t.Fatalf("Expected third instance to be rejected by both nodes: %+v", results[2])
+	}
+	for _, rejection := range results[2].Rejections {
+		if !strings.HasPrefix(rejection.Reason, "altaAffinity:") {
+			t.Errorf("Unexpected rejection reason: %+v", rejection)
+		}
+	}
+
+	// Nothing is allocated, but simulation consumed the resources
+	if nodes[0].Free["cpu"] != 2 || nodes[1].Free["cpu"] != 2 {
+		t.Errorf("Unexpected free cpu after simulation: %v, %v", nodes[0].Free, nodes[1].Free)
+	}
+}
+
+// Test migrations planned together dont overbook a node
+func TestPlanMigration(t *testing.T) {
+	Init()
+
+	newSpec := func(altaId string) *altaspec.AltaSpec {
+		return &altaspec.AltaSpec{AltaId: altaId, NumCpu: 2, Memory: 1024}
+	}
+	newNodes := func() []*NodeInfo {
+		busy := testNode("10.1.1.1", 0, 4096, nil)
+		busy.Altas = []AltaPlacement{
+			{AltaId: "web1", NodeAddr: "10.1.1.1", Resources: map[string]float64{"cpu": 2, "memory": 1024}},
+			{AltaId: "web2", NodeAddr: "10.1.1.1", Resources: map[string]float64{"cpu": 2, "memory": 1024}},
+		}
+		return []*NodeInfo{busy, testNode("10.1.1.2", 2, 4096, nil), testNode("10.1.1.3", 2, 4096, nil)}
+	}
+
+	first, err := planMigrationOnNodes(newSpec("web1"), "10.1.1.1", nil, newNodes())
+	if err != nil {
+		t.Fatalf("Error planning migration. Err: %v", err)
+	}
+
+	// Second alta does not fit where the first one is moving
+	planned := []PlannedMove{{Spec: newSpec("web1"), FromNode: "10.1.1.1", ToNode: first}}
+	second, err := planMigrationOnNodes(newSpec("web2"), "10.1.1.1", planned, newNodes())
+	if err != nil {
+		t.Fatalf("Error planning migration. Err: %v", err)
+	}
+	if (second == first) || (second == "10.1.1.1") {
+		t.Errorf("Both altas planned to move to %s", second)
+	}
+
+	// Alta with a target node is placed only there
+	spec := newSpec("web3")
+	spec.SchedPolicy.TargetNode = "10.1.1.3"
+	nodeList := testRank(t, "", spec, newNodes())
+	if (len(nodeList) != 1) || (nodeList[0] != "10.1.1.3") {
+		t.Errorf("Alta was placed away from its target: %v", nodeList)
+	}
+}
+
+// Test picking lower priority altas to evict
+func TestFindPreemption(t *testing.T) {
+	Init()
+
+	spec := altaspec.AltaSpec{AltaId: "prod", NumCpu: 4, Memory: 1024}
+	spec.SchedPolicy.Priority = 100
+
+	victim := func(altaId string, priority int, cpu float64) AltaPlacement {
+		return AltaPlacement{
+			AltaId:      altaId,
+			Priority:    priority,
+			Resources:   map[string]float64{"cpu": cpu, "memory": 512},
+			Preemptible: true,
+		}
+	}
+
+	// First node needs two dev altas evicted, second one needs a default priority alta
+	node1 := testNode("10.1.1.1", 0, 4096, nil)
+	node1.Altas = []AltaPlacement{victim("dev1", -100, 2), victim("dev2", -100, 2), victim("prod2", 100, 4)}
+	node2 := testNode("10.1.1.2", 2, 4096, nil)
+	node2.Altas = []AltaPlacement{victim("default1", 0, 2)}
+	nodes := []*NodeInfo{node1, node2}
+
+	sched, _ := Scheduler("")
+	preemption, err := findPreemption(sched, &spec, nodes, 10)
+	if err != nil {
+		t.Fatalf("Error finding preemption. Err: %v", err)
+	}
+	if (preemption.NodeAddr != "10.1.1.1") || !reflect.DeepEqual(preemption.Victims, []string{"dev1", "dev2"}) {
+		t.Errorf("Unexpected preemption: %+v", preemption)
+	}
+
+	// Rate limit allows only one eviction
+	preemption, err = findPreemption(sched, &spec, nodes, 1)
+	if err != nil {
+		t.Fatalf("Error finding preemption. Err: %v", err)
+	}
+	if (preemption.NodeAddr != "10.1.1.2") || !reflect.DeepEqual(preemption.Victims, []string{"default1"}) {
+		t.Errorf("Unexpected preemption: %+v", preemption)
+	}
+
+	// Equal or higher priority altas are never evicted
+	spec.SchedPolicy.Priority = 0
+	_, err = findPreemption(sched, &spec, []*NodeInfo{node2}, 10)
+	if err == nil {
+		t.Errorf("Equal priority alta was preempted")
+	}
+}
+
+// Test only recorded evictions count against the rate limit
+func TestEvictionRateLimit(t *testing.T) {
+	Init()
+
+	evictionMutex.Lock()
+	evictionTimes = nil
+	evictionMutex.Unlock()
+
+	spec := altaspec.AltaSpec{AltaId: "prod", NumCpu: 4, Memory: 1024}
+	spec.SchedPolicy.Priority = 100
+	for i := 0; i < MaxEvictionsPerMin; i++ {
+		RecordEviction()
+	}
+	if _, err := FindPreemption(&spec); (err == nil) || (err.Error() != "Eviction rate limit reached") {
+		t.Errorf("Preemption was allowed past the rate limit. Err: %v", err)
+	}
+
+	// Old evictions dont count
+	if pruneEvictions(time.Now().Add(time.Minute)) != 0 {
+		t.Errorf("Evictions older than a minute were counted")
+	}
+}
+
+// Test gang members are placed all or nothing
+func TestPlaceGang(t *testing.T) {
+	Init()
+
+	gangLabels := map[string]string{"gang": "db"}
+	newGang := func(size int) []*altaspec.AltaSpec {
+		var specs []*altaspec.AltaSpec
+		for i := 0; i < size; i++ {
+			spec := altaspec.AltaSpec{
+				AltaId:   fmt.Sprintf("db%d", i),
+				AltaName: fmt.Sprintf("db%d", i),
+				NumCpu:   2,
+				Memory:   1024,
+				Labels:   gangLabels,
+			}
+			spec.SchedPolicy.AntiAffinity = []altaspec.AltaAffinity{{Selector: gangLabels, Hard: true}}
+			specs = append(specs, &spec)
+		}
+		return specs
+	}
+
+	newNodes := func() []*NodeInfo {
+		return []*NodeInfo{testNode("10.1.1.1", 4, 4096, nil), testNode("10.1.1.2", 4, 4096, nil)}
+	}
+
+	// Two members fit on two nodes
+	nodeAddrs := make([]string, 2)
+	rsrcList, err := placeGang(newGang(2), newNodes(), nodeAddrs)
+	if err != nil {
+		t.Fatalf("Error placing gang. Err: %v", err)
+	}
+	if !reflect.DeepEqual(nodeAddrs, []string{"10.1.1.1", "10.1.1.2"}) || (len(rsrcList) != 4) {
+		t.Errorf("Unexpected gang placement %v, resources: %+v", nodeAddrs, rsrcList)
+	}
+
+	// Third member can not be placed, so nothing is allocated
+	rsrcList, err = placeGang(newGang(3), newNodes(), make([]string, 3))
+	if err == nil {
+		t.Fatalf("Gang was placed partially: %+v", rsrcList)
+	}
+	schedErr, ok := err.(*ScheduleError)
+	if !ok || (len(schedErr.Rejections) != 2) || !strings.Contains(schedErr.Message, "db2") {
+		t.Errorf("Unexpected gang error: %v", err)
+	}
+}
@@ -0,0 +1,152 @@
+/***
+Copyright 2014 Cisco Systems Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"github.com/contiv/symphony/pkg/altaspec"
+	"github.com/contiv/symphony/pkg/rsrcMgr"
+	"github.com/contiv/symphony/zeus/common"
+)
+
+// Simulate placing the altas one after another without allocating anything.
+// Each placement consumes resources in the simulation so that later altas
+// see the effect of earlier ones
+func Simulate(specs []*altaspec.AltaSpec) []*common.SimulateResult {
+	nodes := listNodes()
+	fillNodeResources(nodes, listAltaInfo(), rsrcMgr.GetSnapshot())
+
+	return simulateOnNodes(specs, nodes)
+}
+
+// Simulate placement on a list of nodes
+func simulateOnNodes(specs []*altaspec.AltaSpec, nodes []*NodeInfo) []*common.SimulateResult {
+	var results []*common.SimulateResult
+
+	for _, spec := range specs {
+		result := common.SimulateResult{AltaName: spec.AltaName}
+		results = append(results, &result)
+
+		sched, err := Scheduler(spec.SchedPolicy.SchedulerName)
+		if err != nil {
+			result.Error = err.Error()
+			continue
+		}
+
+		// Find the best node
+		nodeList, err := sched.RankNodes(spec, nodes)
+		if err != nil {
+			result.Error = err.Error()
+			if schedErr, ok := err.(*ScheduleError); ok {
+				result.Error = schedErr.Message
+				result.Rejections = schedErr.Rejections
+			}
+			continue
+		}
+		result.NodeAddr = nodeList[0]
+
+		// Consume the resources on the node
+		placeOnNode(nodes, spec, result.NodeAddr)
+	}
+
+	return results
+}
+
+// Alta move that was planned before the one being planned
+type PlannedMove struct {
+	Spec     *altaspec.AltaSpec // Alta being moved
+	FromNode string             // Node the alta moves away from
+	ToNode   string             // Node the alta moves to
+}
+
+// Find the node an alta would move to if it was migrated away from its node.
+// Moves planned earlier are applied first, so that they are not overbooked
+func PlanMigration(spec *altaspec.AltaSpec, fromNode string, planned []PlannedMove) (string, error) {
+	nodes := listNodes()
+	fillNodeResources(nodes, listAltaInfo(), rsrcMgr.GetSnapshot())
+
+	return planMigrationOnNodes(spec, fromNode, planned, nodes)
+}
+
+// Find where the alta would move. Its resources on current node are treated as free
+func planMigrationOnNodes(spec *altaspec.AltaSpec, fromNode string, planned []PlannedMove,
+	nodes []*NodeInfo) (string, error) {
+	sched, err := Scheduler(spec.SchedPolicy.SchedulerName)
+	if err != nil {
+		return "", err
+	}
+
+	// Move the altas we already planned to move
+	for _, move := range planned {
+		removeFromNode(nodes, move.Spec, move.FromNode)
+		placeOnNode(nodes, move.Spec, move.ToNode)
+	}
+
+	// Take the alta off its current node
+	removeFromNode(nodes, spec, fromNode)
+
+	// Rank the other nodes
+	moveSpec := *spec
+	moveSpec.SchedPolicy.AvoidNodes = []string{fromNode}
+	nodeList, err := sched.RankNodes(&moveSpec, nodes)
+	if err != nil {
+		return "", err
+	}
+
+	return nodeList[0], nil
+}
+
+// Place the alta on a node and consume its resources
+func placeOnNode(nodes []*NodeInfo, spec *altaspec.AltaSpec, nodeAddr string) {
+	for _, node := range nodes {
+		if node.HostAddr != nodeAddr {
+			continue
+		}
+
+		for rsrcType, numRsrc := range requestedResources(spec) {
+			node.Free[rsrcType] -= numRsrc
+		}
+		node.Altas = append(node.Altas, AltaPlacement{
+			AltaId:   spec.AltaId,
+			NodeAddr: node.HostAddr,
+			Labels:   spec.Labels,
+			JobName:  spec.JobName,
+
+			Priority:  spec.SchedPolicy.Priority,
+			Resources: requestedResources(spec),
+		})
+	}
+}
+
+// Take the alta off a node and free its resources
+func removeFromNode(nodes []*NodeInfo, spec *altaspec.AltaSpec, nodeAddr string) {
+	for _, node := range nodes {
+		if node.HostAddr != nodeAddr {
+			continue
+		}
+
+		for rsrcType, numRsrc := range requestedResources(spec) {
+			node.Free[rsrcType] += numRsrc
+		}
+
+		var altas []AltaPlacement
+		for _, placement := range node.Altas {
+			if placement.AltaId != spec.AltaId {
+				altas = append(altas, placement)
+			}
+		}
+		node.Altas = altas
+	}
+}
@@ -9,8 +9,10 @@ import (
 	"github.com/contiv/symphony/zeus/altaCtrler"
 	"github.com/contiv/symphony/zeus/api"
 	"github.com/contiv/symphony/zeus/common"
+	"github.com/contiv/symphony/zeus/cronCtrler"
 	"github.com/contiv/symphony/zeus/netCtrler"
 	"github.com/contiv/symphony/zeus/nodeCtrler"
+	"github.com/contiv/symphony/zeus/rebalancer"
 	"github.com/contiv/symphony/zeus/scheduler"
 	"github.com/contiv/symphony/zeus/volumesCtrler"
 
@@ -63,7 +65,7 @@ func runLoopMaster() {
 	}
 
 	// Initialize network controller
-	netCtrler.Init()
+	netCtrler.Init(cdb)
 
 	// Restore state
 	err = volumesCtrler.RestoreVolumes()
@@ -77,8 +79,18 @@ func runLoopMaster() {
 		log.Errorf("Error restoring volumes. Err: %v", err)
 	}
 
+	// Start the cron controller and restore cron jobs
+	ctrlers.CronCtrler = cronCtrler.NewCronCtrler(cdb, ctrlers.AltaCtrler)
+	err = ctrlers.CronCtrler.RestoreCronJobs()
+	if err != nil {
+		log.Errorf("Error restoring cron jobs. Err: %v", err)
+	}
+
+	// Start moving altas off busy nodes
+	ctrlers.Rebalancer = rebalancer.NewRebalancer(ctrlers.AltaCtrler)
+
 	// Start the HTTP server
-	go api.CreateServer(8000, &ctrlers)
+	go api.CreateServer(8000, &ctrlers, cdb)
 
 	log.Infof("Master service is running")
 
@@ -86,6 +98,10 @@ func runLoopMaster() {
 		select {
 		case <-stopMasterChan:
 			log.Infof("Exiting master loop")
+
+			// Stop creating cron jobs, new master will take over
+			ctrlers.CronCtrler.Stop()
+			ctrlers.Rebalancer.Stop()
 			return
 		}
 	}